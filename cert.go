@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"net/http"
@@ -29,6 +32,17 @@ func CertLoad(location string) ([]byte, error) {
 	return CertLoadFromFile(location)
 }
 
+// CertFingerprint returns the SHA-256 fingerprint of a PEM encoded cert, as
+// shown by openssl x509 -fingerprint -sha256 but in lowercase without colons.
+func CertFingerprint(cert []byte) (string, error) {
+	block, _ := pem.Decode(cert)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("cert is not a PEM encoded certificate")
+	}
+	sum := sha256.Sum256(block.Bytes)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
 func CertLoadFromFile(filename string) (cert []byte, err error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
 	"net/url"
 	"testing"
 )
@@ -45,3 +48,20 @@ func TestNormalizeCertURL(t *testing.T) {
 		}
 	}
 }
+
+func TestCertFingerprint(t *testing.T) {
+	certPEM, keyPEM := testKeyPairPEM(t)
+	block, _ := pem.Decode([]byte(certPEM))
+	sum := sha256.Sum256(block.Bytes)
+	expect := "sha256:" + hex.EncodeToString(sum[:])
+	got, err := CertFingerprint([]byte(certPEM))
+	if err != nil || got != expect {
+		t.Errorf("Expected '%s', got '%s' (%v)", expect, got, err)
+	}
+	if _, err := CertFingerprint([]byte(keyPEM)); err == nil {
+		t.Errorf("Expected error for a private key")
+	}
+	if _, err := CertFingerprint([]byte("not a cert")); err == nil {
+		t.Errorf("Expected error for non-PEM input")
+	}
+}
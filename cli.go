@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newApp() *cli.App {
+	cli.VersionPrinter = func(c *cli.Context) {
+		versionInfo().Write(c.App.Writer, OutputFormat_Text)
+	}
+	return &cli.App{
+		Name:                 "kubesealplus",
+		Usage:                "create and rotate SealedSecrets wrapped in Helm conditions",
+		Version:              version,
+		EnableBashCompletion: true,
+		Commands: []*cli.Command{
+			newCommand(),
+			rotateCommand(),
+			addKeyCommand(),
+			removeKeyCommand(),
+			renameKeyCommand(),
+			copyCommand(),
+			moveCommand(),
+			importCommand(),
+			configCommand(),
+			listCommand(),
+			inspectCommand(),
+			diffCommand(),
+			lintCommand(),
+			completionCommand(),
+			versionCommand(),
+		},
+	}
+}
+
+func newCommand() *cli.Command {
+	options := newOptions{}
+	return &cli.Command{
+		Name:      "new",
+		Usage:     "create a new SealedSecret, prompting for its namespace and key-value pairs",
+		ArgsUsage: "(secret-example.environment.yaml)",
+		Flags:     options.flags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			new(c.Args().First(), options)
+			return nil
+		},
+		BashComplete: completeCommand(map[string]func(c *cli.Context){
+			"env":  completeEnvironments,
+			"type": completeSecretTypes,
+		}, func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				completeSecretFiles(c)
+			}
+		}),
+	}
+}
+
+func rotateCommand() *cli.Command {
+	options := rotateOptions{}
+	return &cli.Command{
+		Name:      "rotate",
+		Usage:     "re-enter values for the keys of an existing SealedSecret",
+		ArgsUsage: "(secret-example.environment.yaml)",
+		Flags:     options.flags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			rotate(c.Args().First(), options)
+			return nil
+		},
+		BashComplete: completeCommand(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				completeSecretFiles(c)
+			}
+		}),
+	}
+}
+
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "config",
+		Usage:     "set the cert used to seal secrets for an environment",
+		ArgsUsage: "(environment) cert (file path or URL)",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 3 || c.Args().Get(0) == "" || c.Args().Get(1) != "cert" {
+				return usageError(c)
+			}
+			configure(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2))
+			return nil
+		},
+		BashComplete: completeCommand(nil, func(c *cli.Context, args []string) {
+			switch len(args) {
+			case 0:
+				completeEnvironments(c)
+			case 1:
+				fmt.Fprintln(c.App.Writer, "cert")
+			}
+		}),
+	}
+}
+
+func completionCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "completion",
+		Usage:     "print a shell completion script",
+		ArgsUsage: "(" + strings.Join(completionShells(), "|") + ")",
+		Action: func(c *cli.Context) error {
+			script, exists := completionScripts[c.Args().First()]
+			if c.NArg() != 1 || !exists {
+				return usageError(c)
+			}
+			fmt.Fprint(c.App.Writer, script)
+			return nil
+		},
+		BashComplete: completeCommand(nil, func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				for _, shell := range completionShells() {
+					fmt.Fprintln(c.App.Writer, shell)
+				}
+			}
+		}),
+	}
+}
+
+// Output formats of commands which print data, selected with --output.
+const (
+	OutputFormat_Text = "text"
+	OutputFormat_JSON = "json"
+	OutputFormat_CSV  = "csv"
+)
+
+// outputFlag is the --output flag for a command printing one of the given
+// formats, the first being the default.
+func outputFlag(destination *string, formats ...string) cli.Flag {
+	*destination = formats[0]
+	return &cli.StringFlag{
+		Name:        "output",
+		Aliases:     []string{"o"},
+		Destination: destination,
+		Value:       formats[0],
+		Usage:       "output `format`, one of " + strings.Join(formats, ", "),
+	}
+}
+
+func checkOutputFormat(output string, formats ...string) error {
+	if !contains(formats, output) {
+		return fmt.Errorf("output must be one of %s, got: %s", strings.Join(formats, ", "), output)
+	}
+	return nil
+}
+
+// completeOutputFormats completes --output and -o with the given formats.
+func completeOutputFormats(values map[string]func(c *cli.Context), formats ...string) map[string]func(c *cli.Context) {
+	if values == nil {
+		values = map[string]func(c *cli.Context){}
+	}
+	complete := func(c *cli.Context) {
+		for _, format := range formats {
+			fmt.Fprintln(c.App.Writer, format)
+		}
+	}
+	values["output"] = complete
+	values["o"] = complete
+	return values
+}
+
+// usageError shows the help of the command being run and returns an error
+// for its usage.
+func usageError(c *cli.Context) error {
+	cli.ShowCommandHelp(c, c.Command.Name)
+	return cli.Exit(fmt.Sprintf("\nIncorrect usage of %s", c.Command.Name), 1)
+}
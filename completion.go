@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// completionScripts are printed by the completion command. Each asks
+// kubesealplus itself for the candidates, via --generate-bash-completion.
+var completionScripts = map[string]string{
+	"bash": `_kubesealplus_complete() {
+  local cur opts
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  if [[ "$cur" == "-"* ]]; then
+    opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} ${cur} --generate-bash-completion 2>/dev/null )
+  else
+    opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} --generate-bash-completion 2>/dev/null )
+  fi
+  COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+  return 0
+}
+
+complete -o bashdefault -o default -F _kubesealplus_complete kubesealplus
+`,
+	"zsh": `#compdef kubesealplus
+
+_kubesealplus_complete() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion 2>/dev/null)}")
+  else
+    opts=("${(@f)$(${words[@]:0:#words[@]-1} --generate-bash-completion 2>/dev/null)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _kubesealplus_complete kubesealplus
+`,
+	"fish": `function __kubesealplus_complete
+  set -l args (commandline -opc)
+  set -l cur (commandline -ct)
+  if string match -q -- '-*' $cur
+    $args $cur --generate-bash-completion 2>/dev/null
+  else
+    $args --generate-bash-completion 2>/dev/null
+  end
+end
+
+complete -c kubesealplus -f -a '(__kubesealplus_complete)'
+`,
+}
+
+func completionShells() []string {
+	shells := []string{}
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
+
+// completeCommand completes the value of a flag by name using values, the
+// flags of the command for a word starting with -, and otherwise the next
+// positional argument given those before it.
+func completeCommand(values map[string]func(c *cli.Context), positional func(c *cli.Context, args []string)) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		args := os.Args
+		if len(args) > 0 && args[len(args)-1] == "--generate-bash-completion" {
+			args = args[:len(args)-1]
+		}
+		if last := args[len(args)-1]; strings.HasPrefix(last, "-") {
+			name := strings.TrimLeft(last, "-")
+			if complete, exists := values[name]; exists {
+				complete(c)
+				return
+			}
+			for _, flag := range c.Command.Flags {
+				if _, isBool := flag.(*cli.BoolFlag); !isBool && contains(flag.Names(), name) {
+					return
+				}
+			}
+			cli.DefaultCompleteWithFlags(c.Command)(c)
+			return
+		}
+		positional(c, c.Args().Slice())
+	}
+}
+
+// completeEnvironments lists the environments configured with a cert.
+func completeEnvironments(c *cli.Context) {
+	configFile, err := ConfigFileDefaultPath("")
+	if err != nil {
+		return
+	}
+	configDoc := ConfigDoc{}
+	if !configDoc.Exists(configFile) || configDoc.Load(configFile) != nil {
+		return
+	}
+	environments := []string{}
+	for environment := range configDoc.Environments {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+	for _, environment := range environments {
+		fmt.Fprintln(c.App.Writer, environment)
+	}
+}
+
+func completeSecretTypes(c *cli.Context) {
+	for _, name := range secretTypeBuilderNames() {
+		fmt.Fprintln(c.App.Writer, name)
+	}
+}
+
+func completeSecretFiles(c *cli.Context) {
+	for _, filename := range secretFiles(".") {
+		fmt.Fprintln(c.App.Writer, filename)
+	}
+}
+
+func completeKeys(c *cli.Context, filename string, target targetOptions) {
+	for _, key := range secretFileKeys(filename, target) {
+		fmt.Fprintln(c.App.Writer, key)
+	}
+}
+
+// secretFiles finds the files under root named per the filename convention
+// of their project, skipping hidden directories.
+func secretFiles(root string) (filenames []string) {
+	projects := map[string]ProjectConfig{}
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dir := filepath.Dir(path)
+		project, exists := projects[dir]
+		if !exists {
+			project, err = ProjectConfigLoad(path)
+			if err != nil {
+				project = ProjectConfigDefault()
+			}
+			projects[dir] = project
+		}
+		if _, _, err := project.Filenames.NameAndEnvironment(path); err == nil {
+			filenames = append(filenames, path)
+		}
+		return nil
+	})
+	return
+}
+
+// secretFileKeys returns the spec.encryptedData keys of the SealedSecret in a
+// file, or of every SealedSecret for the environment if it is ambiguous.
+func secretFileKeys(filename string, target targetOptions) []string {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	project, err := ProjectConfigLoad(filename)
+	if err != nil {
+		return nil
+	}
+	_, environment, err := target.resolve(filename, project)
+	if err != nil {
+		return nil
+	}
+	t, err := parseSealedSecretTemplate(filename, environment, string(content), project)
+	if err != nil {
+		return nil
+	}
+	if t.Document == nil && target.name != "" {
+		t.Select(target.name)
+	}
+	documents := []*yamlDocument{t.Document}
+	if t.Document == nil {
+		documents = t.Block(project.Environments(environment)).Documents
+	}
+	keys := []string{}
+	for _, document := range documents {
+		for _, key := range document.Keys("spec", "encryptedData") {
+			if !contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
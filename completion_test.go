@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, filename string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a", "templates", "secret-db.production.yaml"), "")
+	writeTestFile(t, filepath.Join(dir, "a", "templates", "deployment.yaml"), "")
+	writeTestFile(t, filepath.Join(dir, "b", ProjectConfigFilename), "filenames:\n  pattern: templates/{env}/{name}-sealed.{ext}\n")
+	writeTestFile(t, filepath.Join(dir, "b", "templates", "staging", "api-sealed.yml"), "")
+	writeTestFile(t, filepath.Join(dir, "b", "templates", "secret-db.production.yaml"), "")
+	writeTestFile(t, filepath.Join(dir, ".git", "secret-db.production.yaml"), "")
+
+	expect := []string{
+		filepath.Join(dir, "a", "templates", "secret-db.production.yaml"),
+		filepath.Join(dir, "b", "templates", "staging", "api-sealed.yml"),
+	}
+	if got := secretFiles(dir); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %v, got %v", expect, got)
+	}
+}
+
+func TestSecretFileKeys(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "secret-db.production.yaml")
+	writeTestFile(t, filename, testMultiTemplate)
+	if got := secretFileKeys(filename, targetOptions{}); !reflect.DeepEqual(got, []string{"PASSWORD", "TOKEN"}) {
+		t.Errorf("Expected keys of every SealedSecret, got %v", got)
+	}
+	if got := secretFileKeys(filename, targetOptions{name: "api-secret"}); !reflect.DeepEqual(got, []string{"TOKEN"}) {
+		t.Errorf("Expected keys of api-secret, got %v", got)
+	}
+	single := filepath.Join(dir, "secret-single.testing.yaml")
+	writeTestFile(t, single, "{{- if eq .Values.environment \"testing\" }}\n"+testErrorBody+"{{- end }}\n")
+	if got := secretFileKeys(single, targetOptions{}); !reflect.DeepEqual(got, []string{"MESSAGE"}) {
+		t.Errorf("Expected [MESSAGE], got %v", got)
+	}
+	if got := secretFileKeys(filepath.Join(dir, "missing.yaml"), targetOptions{}); got != nil {
+		t.Errorf("Expected no keys for a missing file, got %v", got)
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	for _, shell := range completionShells() {
+		app := newApp()
+		out := &bytes.Buffer{}
+		app.Writer = out
+		if err := app.Run([]string{"kubesealplus", "completion", shell}); err != nil {
+			t.Errorf("Unexpected error for %s: %s", shell, err)
+		}
+		if !strings.Contains(out.String(), "--generate-bash-completion") {
+			t.Errorf("Expected %s script to call --generate-bash-completion, got:\n%s", shell, out.String())
+		}
+	}
+}
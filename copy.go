@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// copySealedSecret returns a SealedSecret for environment with the same name,
+// namespace, type, labels, annotations and template as source but no
+// ciphertext, which is only valid for the cert it was sealed with.
+func copySealedSecret(source SealedSecret, environment string) SealedSecret {
+	sealedSecret := SealedSecret{Environment: environment}
+	sealedSecret.Init(source.Metadata.Name, source.Metadata.Namespace)
+	sealedSecret.Metadata.Labels = copyMap(source.Metadata.Labels)
+	sealedSecret.Metadata.Annotations = copyMap(source.Metadata.Annotations)
+	delete(sealedSecret.Metadata.Annotations, SealedSecretAnnotation_LastRotated)
+	delete(sealedSecret.Metadata.Annotations, SealedSecretAnnotation_CertFingerprint)
+	if len(sealedSecret.Metadata.Annotations) == 0 {
+		sealedSecret.Metadata.Annotations = nil
+	}
+	template := source.Spec.Template
+	sealedSecret.Spec.Template.Type = template.Type
+	sealedSecret.Spec.Template.Metadata.Labels = copyMap(template.Metadata.Labels)
+	sealedSecret.Spec.Template.Metadata.Annotations = copyMap(template.Metadata.Annotations)
+	if template.Immutable != nil {
+		immutable := *template.Immutable
+		sealedSecret.Spec.Template.Immutable = &immutable
+	}
+	if template.Data != nil {
+		data := copyMap(*template.Data)
+		sealedSecret.Spec.Template.Data = &data
+	}
+	return sealedSecret
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := map[string]string{}
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// copyTarget returns the path of the file for environment, alongside the
+// file being copied.
+func copyTarget(filename string, environment string, project ProjectConfig) (string, error) {
+	if !isValidEnv(environment) {
+		return "", fmt.Errorf("invalid environment name: %s", environment)
+	}
+	name, sourceEnvironment, err := project.Filenames.NameAndEnvironment(filename)
+	if err != nil {
+		return "", err
+	}
+	if environment == sourceEnvironment {
+		return "", fmt.Errorf("%s is already for environment %s", filename, environment)
+	}
+	return project.Filenames.Rename(filename, name, environment)
+}
+
+func copySecret(filename string, environment string, target targetOptions) {
+	file, t, source, project, _ := openSealedSecret(filename, target, "copy")
+	file.Close()
+	targetFilename, err := copyTarget(filename, environment, project)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(targetFilename); err == nil {
+		fmt.Printf("Error: cannot copy to %s as file already exists\n", targetFilename)
+		os.Exit(1)
+	}
+	condition := project.HelmCondition(environment)
+	sealedSecret := copySealedSecret(source, environment)
+
+	fmt.Printf("Enter values for %s to be sealed with the cert for %s.\n", targetFilename, environment)
+	var certFingerprint string
+	sealedSecret.Spec.EncryptedData, certFingerprint = sealKeys(&sealedSecret, project, condition, t.Document.Keys("spec", "encryptedData"))
+	if project.RecordRotation {
+		sealedSecret.RecordRotation(time.Now(), certFingerprint)
+	}
+
+	out, err := os.Create(targetFilename)
+	if err != nil {
+		fmt.Printf("Error creating new file: %s\n", err)
+		os.Exit(1)
+	}
+	defer out.Close()
+	content, err := sealedSecret.ToTemplate(out, condition, project.Output)
+	if err != nil {
+		fmt.Printf("error writing SealedSecret file %s: %s\n", targetFilename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created SealedSecret file '%s' with content:\n%s", targetFilename, content.String())
+}
+
+func copyCommand() *cli.Command {
+	target := targetOptions{}
+	return &cli.Command{
+		Name:      "copy",
+		Usage:     "create the file for another environment with the same SealedSecret, prompting for fresh values",
+		ArgsUsage: "(secret-example.environment.yaml) (target-environment)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "name", Destination: &target.name, Usage: "copy the SealedSecret named `name`, where the file has several"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			copySecret(c.Args().Get(0), c.Args().Get(1), target)
+			return nil
+		},
+		BashComplete: completeCommand(nil, func(c *cli.Context, args []string) {
+			switch len(args) {
+			case 0:
+				completeSecretFiles(c)
+			case 1:
+				completeEnvironments(c)
+			}
+		}),
+	}
+}
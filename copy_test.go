@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCopySealedSecret(t *testing.T) {
+	immutable := true
+	data := map[string]string{"url": "postgres://app:{{ .PASSWORD }}@db/app"}
+	source := SealedSecret{Environment: "staging"}
+	source.Init("db-secret", "app")
+	source.Metadata.Labels = map[string]string{"team": "payments"}
+	source.Metadata.Annotations = map[string]string{
+		SealedSecretAnnotation_NamespaceWide:   "true",
+		SealedSecretAnnotation_LastRotated:     "2023-03-01T01:00:00Z",
+		SealedSecretAnnotation_CertFingerprint: "sha256:abc",
+	}
+	source.Spec.EncryptedData = map[string]string{"PASSWORD": "AgStaging=="}
+	source.Spec.Template.Type = "kubernetes.io/basic-auth"
+	source.Spec.Template.Immutable = &immutable
+	source.Spec.Template.Metadata.Labels = map[string]string{"app": "db"}
+	source.Spec.Template.Data = &data
+
+	copied := copySealedSecret(source, "production")
+
+	expect := SealedSecret{Environment: "production"}
+	expect.Init("db-secret", "app")
+	expect.Metadata.Labels = map[string]string{"team": "payments"}
+	expect.Metadata.Annotations = map[string]string{SealedSecretAnnotation_NamespaceWide: "true"}
+	expect.Spec.Template.Type = "kubernetes.io/basic-auth"
+	expect.Spec.Template.Immutable = &immutable
+	expect.Spec.Template.Metadata.Labels = map[string]string{"app": "db"}
+	expect.Spec.Template.Data = &data
+	if !reflect.DeepEqual(copied, expect) {
+		t.Errorf("Expected:\n%+v\nGot:\n%+v", expect, copied)
+	}
+
+	copied.Metadata.Labels["team"] = "changed"
+	(*copied.Spec.Template.Data)["url"] = "changed"
+	if source.Metadata.Labels["team"] != "payments" || (*source.Spec.Template.Data)["url"] == "changed" {
+		t.Errorf("Expected copy not to share maps with the source")
+	}
+}
+
+func TestCopyTarget(t *testing.T) {
+	tests := []struct {
+		filename    string
+		environment string
+		expect      string
+		err         string
+	}{
+		{"templates/secret-db.staging.yaml", "production", "templates/secret-db.production.yaml", ""},
+		{"templates/secret-db.staging.yaml", "staging", "", "already for environment staging"},
+		{"templates/secret-db.staging.yaml", "Prod!", "", "invalid environment name"},
+		{"templates/db.yaml", "production", "", "db.yaml"},
+	}
+	for _, test := range tests {
+		target, err := copyTarget(test.filename, test.environment, ProjectConfigDefault())
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Expected error containing '%s' for %s, got: %v", test.err, test.filename, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %s", test.filename, err)
+		} else if target != test.expect {
+			t.Errorf("Expected %s, got %s", test.expect, target)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Status of a SealedSecret in a diff.
+const (
+	DiffStatus_Added     = "added"
+	DiffStatus_Removed   = "removed"
+	DiffStatus_Changed   = "changed"
+	DiffStatus_Unchanged = "unchanged"
+)
+
+// DiffResult compares the SealedSecrets of a file at a git revision with the
+// working tree. Ciphertext changes every time a value is sealed, so a key with
+// different ciphertext is reported as re-sealed rather than as a changed value.
+type DiffResult struct {
+	File          string             `json:"file"`
+	Against       string             `json:"against"`
+	SealedSecrets []DiffSealedSecret `json:"sealedSecrets"`
+}
+
+type DiffSealedSecret struct {
+	Environments  []string     `json:"environments"`
+	Name          string       `json:"name"`
+	Status        string       `json:"status"`
+	KeysAdded     []string     `json:"keysAdded"`
+	KeysRemoved   []string     `json:"keysRemoved"`
+	KeysResealed  []string     `json:"keysResealed"`
+	KeysUnchanged []string     `json:"keysUnchanged"`
+	Metadata      []DiffChange `json:"metadata"`
+}
+
+// DiffChange is a change to a metadata field, where an empty Old or New
+// means the field was added or removed.
+type DiffChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+func (r DiffResult) HasChanges() bool {
+	for _, s := range r.SealedSecrets {
+		if s.Status != DiffStatus_Unchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// gitShow returns the content of a file at a git revision, and whether the
+// file exists at that revision.
+func gitShow(filename string, ref string) (content string, exists bool, err error) {
+	dir := filepath.Dir(filename)
+	verify := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err = verify.Run(); err != nil {
+		err = fmt.Errorf("'%s' is not a git revision in %s", ref, dir)
+		return
+	}
+	var stdout, stderr bytes.Buffer
+	show := exec.Command("git", "-C", dir, "show", ref+":./"+filepath.Base(filename))
+	show.Stdout = &stdout
+	show.Stderr = &stderr
+	if err = show.Run(); err != nil {
+		message := stderr.String()
+		if strings.Contains(message, "does not exist") || strings.Contains(message, "exists on disk, but not in") {
+			return "", false, nil
+		}
+		err = fmt.Errorf("git show %s:%s failed: %s", ref, filename, strings.TrimSpace(message))
+		return
+	}
+	return stdout.String(), true, nil
+}
+
+// diffSealedSecretFile compares the SealedSecrets of a file at ref with the
+// working tree.
+func diffSealedSecretFile(filename string, ref string, target targetOptions) (result DiffResult, err error) {
+	result = DiffResult{File: filename, Against: ref, SealedSecrets: []DiffSealedSecret{}}
+	oldContent, oldExists, err := gitShow(filename, ref)
+	if err != nil {
+		return
+	}
+	newContent, err := os.ReadFile(filename)
+	newExists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("cannot read file: %s", filename)
+		return
+	}
+	if !oldExists && !newExists {
+		err = fmt.Errorf("%s does not exist at %s or in the working tree", filename, ref)
+		return
+	}
+	project, err := ProjectConfigLoad(filename)
+	if err != nil {
+		return
+	}
+	_, environment, err := target.resolve(filename, project)
+	if err != nil {
+		return
+	}
+	var oldSealedSecrets, newSealedSecrets []diffEntry
+	if oldExists {
+		oldSealedSecrets, err = diffEntries(fmt.Sprintf("%s:%s", ref, filename), environment, oldContent, project)
+		if err != nil {
+			return
+		}
+	}
+	if newExists {
+		newSealedSecrets, err = diffEntries(filename, environment, string(newContent), project)
+		if err != nil {
+			return
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, o := range oldSealedSecrets {
+		seen[o.id()] = true
+		var n *diffEntry
+		for i := range newSealedSecrets {
+			if newSealedSecrets[i].id() == o.id() {
+				n = &newSealedSecrets[i]
+			}
+		}
+		result.SealedSecrets = append(result.SealedSecrets, diffSealedSecret(&o, n))
+	}
+	for i, n := range newSealedSecrets {
+		if !seen[n.id()] {
+			result.SealedSecrets = append(result.SealedSecrets, diffSealedSecret(nil, &newSealedSecrets[i]))
+		}
+	}
+	return
+}
+
+// diffEntry is a SealedSecret along with the environments of its block.
+type diffEntry struct {
+	environments []string
+	sealedSecret SealedSecret
+}
+
+func (e diffEntry) id() string {
+	environments := append([]string{}, e.environments...)
+	sort.Strings(environments)
+	return strings.Join(environments, ",") + "/" + e.sealedSecret.Metadata.Name
+}
+
+func diffEntries(filename string, environment string, content string, project ProjectConfig) (entries []diffEntry, err error) {
+	t, err := parseTemplateFile(filename, environment, content, project)
+	if err != nil {
+		return
+	}
+	for _, block := range t.Blocks {
+		for i, document := range block.Documents {
+			var sealedSecret SealedSecret
+			if sealedSecret, err = decodeSealedSecret(document); err != nil {
+				err = fmt.Errorf("%s:%d: not a valid SealedSecret: %s", filename, block.DocumentLines[i], err)
+				return
+			}
+			entries = append(entries, diffEntry{environments: block.Environments, sealedSecret: sealedSecret})
+		}
+	}
+	return
+}
+
+func diffSealedSecret(o *diffEntry, n *diffEntry) DiffSealedSecret {
+	var oldData, newData map[string]string
+	var oldFields, newFields map[string]string
+	d := DiffSealedSecret{
+		KeysAdded:     []string{},
+		KeysRemoved:   []string{},
+		KeysResealed:  []string{},
+		KeysUnchanged: []string{},
+		Metadata:      []DiffChange{},
+	}
+	if o != nil {
+		d.Environments, d.Name = o.environments, o.sealedSecret.Metadata.Name
+		oldData, oldFields = o.sealedSecret.Spec.EncryptedData, diffFields(o.sealedSecret)
+	}
+	if n != nil {
+		d.Environments, d.Name = n.environments, n.sealedSecret.Metadata.Name
+		newData, newFields = n.sealedSecret.Spec.EncryptedData, diffFields(n.sealedSecret)
+	}
+	for _, key := range sortedKeys(mergeMaps(oldData, newData)) {
+		oldValue, inOld := oldData[key]
+		newValue, inNew := newData[key]
+		switch {
+		case !inOld:
+			d.KeysAdded = append(d.KeysAdded, key)
+		case !inNew:
+			d.KeysRemoved = append(d.KeysRemoved, key)
+		case oldValue != newValue:
+			d.KeysResealed = append(d.KeysResealed, key)
+		default:
+			d.KeysUnchanged = append(d.KeysUnchanged, key)
+		}
+	}
+	if o != nil && n != nil {
+		for _, field := range sortedKeys(mergeMaps(oldFields, newFields)) {
+			if oldFields[field] != newFields[field] {
+				d.Metadata = append(d.Metadata, DiffChange{Field: field, Old: oldFields[field], New: newFields[field]})
+			}
+		}
+	}
+	switch {
+	case o == nil:
+		d.Status = DiffStatus_Added
+	case n == nil:
+		d.Status = DiffStatus_Removed
+	case len(d.KeysAdded)+len(d.KeysRemoved)+len(d.KeysResealed)+len(d.Metadata) > 0:
+		d.Status = DiffStatus_Changed
+	default:
+		d.Status = DiffStatus_Unchanged
+	}
+	return d
+}
+
+// diffFields flattens everything but the ciphertext of a SealedSecret into
+// fields which can be compared.
+func diffFields(s SealedSecret) map[string]string {
+	fields := map[string]string{
+		"metadata.namespace":               s.Metadata.Namespace,
+		"spec.template.metadata.name":      s.Spec.Template.Metadata.Name,
+		"spec.template.metadata.namespace": s.Spec.Template.Metadata.Namespace,
+		"spec.template.type":               s.Spec.Template.Type,
+	}
+	if s.Spec.Template.Immutable != nil {
+		fields["spec.template.immutable"] = strconv.FormatBool(*s.Spec.Template.Immutable)
+	}
+	prefixed := map[string]map[string]string{
+		"metadata.labels":                    s.Metadata.Labels,
+		"metadata.annotations":               s.Metadata.Annotations,
+		"spec.template.metadata.labels":      s.Spec.Template.Metadata.Labels,
+		"spec.template.metadata.annotations": s.Spec.Template.Metadata.Annotations,
+	}
+	if s.Spec.Template.Data != nil {
+		prefixed["spec.template.data"] = *s.Spec.Template.Data
+	}
+	for prefix, m := range prefixed {
+		for k, v := range m {
+			fields[prefix+"."+k] = v
+		}
+	}
+	for k, v := range fields {
+		if v == "" {
+			delete(fields, k)
+		}
+	}
+	return fields
+}
+
+func mergeMaps(a map[string]string, b map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
+
+func (r DiffResult) Write(w io.Writer, output string) error {
+	switch output {
+	case OutputFormat_Text:
+		fmt.Fprintf(w, "%s (%s -> working tree)\n", r.File, r.Against)
+		if len(r.SealedSecrets) == 0 {
+			fmt.Fprintf(w, "  no SealedSecrets\n")
+		}
+		for _, s := range r.SealedSecrets {
+			fmt.Fprintf(w, "%s %s: %s\n", strings.Join(s.Environments, ","), s.Name, s.Status)
+			for _, keys := range []struct {
+				label string
+				keys  []string
+			}{
+				{"added:    ", s.KeysAdded},
+				{"removed:  ", s.KeysRemoved},
+				{"re-sealed:", s.KeysResealed},
+				{"unchanged:", s.KeysUnchanged},
+			} {
+				if len(keys.keys) > 0 {
+					fmt.Fprintf(w, "  %s %s\n", keys.label, strings.Join(keys.keys, ", "))
+				}
+			}
+			for _, change := range s.Metadata {
+				switch {
+				case change.Old == "":
+					fmt.Fprintf(w, "  %s: added %q\n", change.Field, change.New)
+				case change.New == "":
+					fmt.Fprintf(w, "  %s: removed %q\n", change.Field, change.Old)
+				default:
+					fmt.Fprintf(w, "  %s: %q -> %q\n", change.Field, change.Old, change.New)
+				}
+			}
+		}
+		return nil
+	case OutputFormat_JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+	return checkOutputFormat(output, OutputFormat_Text, OutputFormat_JSON)
+}
+
+func diffCommand() *cli.Command {
+	target := targetOptions{}
+	var output, against string
+	var exitCode bool
+	formats := []string{OutputFormat_Text, OutputFormat_JSON}
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "show which keys and metadata of a SealedSecret file changed since a git revision",
+		ArgsUsage: "(secret-example.environment.yaml)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "against", Destination: &against, Value: "HEAD", Usage: "git `revision` to compare the working tree with"},
+			&cli.StringFlag{Name: "env", Destination: &target.environment, Usage: "`environment` (default taken from the filename)"},
+			&cli.BoolFlag{Name: "exit-code", Destination: &exitCode, Usage: "exit with status 1 if there are changes"},
+			outputFlag(&output, formats...),
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			if err := checkOutputFormat(output, formats...); err != nil {
+				return err
+			}
+			result, err := diffSealedSecretFile(c.Args().First(), against, target)
+			if err == nil {
+				err = result.Write(c.App.Writer, output)
+			}
+			if err == nil && exitCode && result.HasChanges() {
+				return cli.Exit("", 1)
+			}
+			return err
+		},
+		BashComplete: completeCommand(completeOutputFormats(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, formats...), func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				completeSecretFiles(c)
+			}
+		}),
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffSealedSecret(t *testing.T) {
+	old := SealedSecret{}
+	old.Init("db-secret", "example")
+	old.Spec.EncryptedData = map[string]string{"A": "AgOld==", "B": "AgB==", "C": "AgC=="}
+	new := old
+	new.Metadata.Namespace = "other"
+	new.Metadata.Labels = map[string]string{"app": "db"}
+	new.Spec.EncryptedData = map[string]string{"A": "AgNew==", "B": "AgB==", "D": "AgD=="}
+
+	got := diffSealedSecret(
+		&diffEntry{environments: []string{"production"}, sealedSecret: old},
+		&diffEntry{environments: []string{"production"}, sealedSecret: new},
+	)
+	expect := DiffSealedSecret{
+		Environments:  []string{"production"},
+		Name:          "db-secret",
+		Status:        DiffStatus_Changed,
+		KeysAdded:     []string{"D"},
+		KeysRemoved:   []string{"C"},
+		KeysResealed:  []string{"A"},
+		KeysUnchanged: []string{"B"},
+		Metadata: []DiffChange{
+			{Field: "metadata.labels.app", New: "db"},
+			{Field: "metadata.namespace", Old: "example", New: "other"},
+		},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected:\n%+v\nGot:\n%+v", expect, got)
+	}
+
+	got = diffSealedSecret(&diffEntry{environments: []string{"production"}, sealedSecret: old}, nil)
+	if got.Status != DiffStatus_Removed || !reflect.DeepEqual(got.KeysRemoved, []string{"A", "B", "C"}) {
+		t.Errorf("Expected all keys removed, got %+v", got)
+	}
+	got = diffSealedSecret(nil, &diffEntry{environments: []string{"production"}, sealedSecret: new})
+	if got.Status != DiffStatus_Added || len(got.Metadata) != 0 {
+		t.Errorf("Expected all keys added without metadata changes, got %+v", got)
+	}
+}
+
+func TestDiffSealedSecretFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	filename := filepath.Join(dir, "secret-db.production.yaml")
+	writeTestFile(t, filename, testMultiTemplate)
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	writeTestFile(t, filename, strings.Replace(testMultiTemplate, "AgProductionDb==", "AgRotatedDb==", 1))
+
+	result, err := diffSealedSecretFile(filename, "HEAD", targetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	statuses := []string{}
+	for _, s := range result.SealedSecrets {
+		statuses = append(statuses, strings.Join(s.Environments, ",")+"/"+s.Name+"="+s.Status)
+	}
+	expect := []string{"production/db-secret=changed", "production/api-secret=unchanged", "staging/db-secret=unchanged"}
+	if !reflect.DeepEqual(statuses, expect) {
+		t.Errorf("Expected %v, got %v", expect, statuses)
+	}
+	if !reflect.DeepEqual(result.SealedSecrets[0].KeysResealed, []string{"PASSWORD"}) || !result.HasChanges() {
+		t.Errorf("Expected PASSWORD re-sealed, got %+v", result.SealedSecrets[0])
+	}
+
+	added := filepath.Join(dir, "secret-api.production.yaml")
+	writeTestFile(t, added, testMultiTemplate)
+	result, err = diffSealedSecretFile(added, "HEAD", targetOptions{})
+	if err != nil || len(result.SealedSecrets) != 3 || result.SealedSecrets[0].Status != DiffStatus_Added {
+		t.Errorf("Expected every SealedSecret added for a new file, got %+v (%v)", result.SealedSecrets, err)
+	}
+
+	if _, err = diffSealedSecretFile(filename, "no-such-ref", targetOptions{}); err == nil {
+		t.Errorf("Expected error for an unknown revision")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DotenvEntry is a key and value from a dotenv file, along with the line the
+// key is on.
+type DotenvEntry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// loadDotenv reads a dotenv file, see parseDotenv.
+func loadDotenv(filename string) (entries []DotenvEntry, err error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %s", filename)
+	}
+	entries, err = parseDotenv(string(content))
+	if err != nil {
+		err = fmt.Errorf("%s:%s", filename, err)
+	}
+	return
+}
+
+// parseDotenv parses KEY=value lines, optionally prefixed by `export`.
+// Values may be unquoted (trimmed, with a ` #` comment removed), single
+// quoted (taken literally) or double quoted (with \n, \r, \t, \", \\ and \$
+// escapes); quoted values may span several lines. Lines starting with # are
+// comments. Variables are not expanded.
+func parseDotenv(content string) (entries []DotenvEntry, err error) {
+	p := dotenvParser{content: strings.ReplaceAll(content, "\r\n", "\n"), line: 1}
+	lines := map[string]int{}
+	for {
+		p.skip(" \t\n")
+		if p.done() {
+			break
+		}
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+		entry := DotenvEntry{Line: p.line}
+		entry.Key = p.until(" \t\n=")
+		if entry.Key == "export" && strings.ContainsRune(" \t", p.peek()) {
+			p.skip(" \t")
+			entry.Key = p.until(" \t\n=")
+		}
+		p.skip(" \t")
+		if entry.Key == "" || p.peek() != '=' {
+			return nil, fmt.Errorf("%d: expected KEY=value", entry.Line)
+		}
+		p.pos++
+		p.skip(" \t")
+		entry.Value, err = p.value()
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s", entry.Line, err)
+		}
+		if err = validateSecretKey(entry.Key); err != nil {
+			return nil, fmt.Errorf("%d: %s", entry.Line, err)
+		}
+		if line, exists := lines[entry.Key]; exists {
+			return nil, fmt.Errorf("%d: %s is already set on line %d", entry.Line, entry.Key, line)
+		}
+		lines[entry.Key] = entry.Line
+		entries = append(entries, entry)
+	}
+	return
+}
+
+type dotenvParser struct {
+	content string
+	pos     int
+	line    int
+}
+
+func (p *dotenvParser) done() bool {
+	return p.pos >= len(p.content)
+}
+
+func (p *dotenvParser) peek() rune {
+	if p.done() {
+		return 0
+	}
+	return rune(p.content[p.pos])
+}
+
+func (p *dotenvParser) next() byte {
+	c := p.content[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skip moves past any of chars.
+func (p *dotenvParser) skip(chars string) {
+	for !p.done() && strings.ContainsRune(chars, p.peek()) {
+		p.next()
+	}
+}
+
+// until returns the text up to any of chars or the end of the content.
+func (p *dotenvParser) until(chars string) string {
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(chars, p.peek()) {
+		p.next()
+	}
+	return p.content[start:p.pos]
+}
+
+func (p *dotenvParser) skipLine() {
+	p.until("\n")
+}
+
+// value reads the value of an entry, up to and including the end of its
+// last line.
+func (p *dotenvParser) value() (value string, err error) {
+	switch quote := p.peek(); quote {
+	case '\'', '"':
+		p.next()
+		builder := strings.Builder{}
+		for {
+			if p.done() {
+				return "", fmt.Errorf("value is missing its closing %c", quote)
+			}
+			c := p.next()
+			if c == byte(quote) {
+				break
+			}
+			if c == '\\' && quote == '"' && !p.done() {
+				escaped := p.next()
+				switch escaped {
+				case 'n':
+					builder.WriteByte('\n')
+				case 'r':
+					builder.WriteByte('\r')
+				case 't':
+					builder.WriteByte('\t')
+				case '"', '\\', '$':
+					builder.WriteByte(escaped)
+				default:
+					builder.WriteByte('\\')
+					builder.WriteByte(escaped)
+				}
+				continue
+			}
+			builder.WriteByte(c)
+		}
+		p.skip(" \t")
+		if !p.done() && p.peek() != '\n' && p.peek() != '#' {
+			return "", fmt.Errorf("unexpected text after the closing %c", quote)
+		}
+		p.skipLine()
+		return builder.String(), nil
+	}
+	value = p.until("\n")
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	if i := strings.Index(value, "\t#"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
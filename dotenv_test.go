@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	content := "" +
+		"# database\n" +
+		"DB_USER=app\n" +
+		"export DB_PASSWORD = 'p@ss#word $HOME'\n" +
+		"\n" +
+		"API_URL=https://example.com/#anchor # the API\n" +
+		"GREETING=\"hello\\n\\\"world\\\" \\$USER\\\\\" # comment\n" +
+		"CERT=\"-----BEGIN CERT-----\n" +
+		"abc\n" +
+		"-----END CERT-----\"\n" +
+		"SPACED=  padded value  \r\n" +
+		"EMPTY=\n" +
+		"export=value\n"
+	entries, err := parseDotenv(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := []DotenvEntry{
+		{"DB_USER", "app", 2},
+		{"DB_PASSWORD", "p@ss#word $HOME", 3},
+		{"API_URL", "https://example.com/#anchor", 5},
+		{"GREETING", "hello\n\"world\" $USER\\", 6},
+		{"CERT", "-----BEGIN CERT-----\nabc\n-----END CERT-----", 7},
+		{"SPACED", "padded value", 10},
+		{"EMPTY", "", 11},
+		{"export", "value", 12},
+	}
+	if !reflect.DeepEqual(entries, expect) {
+		t.Errorf("Expected:\n%#v\nGot:\n%#v", expect, entries)
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"KEY\n", "1: expected KEY=value"},
+		{"\n=value\n", "2: expected KEY=value"},
+		{"KEY=\"unterminated\nvalue\n", "1: value is missing its closing \""},
+		{"KEY='value' extra\n", "1: unexpected text after the closing '"},
+		{"A=1\nA=2\n", "2: A is already set on line 1"},
+		{"BAD KEY=1\n", "1: expected KEY=value"},
+		{"BAD/KEY=1\n", "1: "},
+	}
+	for _, test := range tests {
+		_, err := parseDotenv(test.content)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Expected error starting '%s' for %q, got: %v", test.err, test.content, err)
+		}
+	}
+}
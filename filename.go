@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Placeholders of a filename pattern.
+const (
+	FilenamePlaceholder_Name        = "{name}"
+	FilenamePlaceholder_Environment = "{env}"
+	FilenamePlaceholder_Extension   = "{ext}"
+)
+
+const (
+	FilenamePattern_Default    = "secret-{name}.{env}.yaml"
+	FilenameNameSuffix_Default = "-secret"
+)
+
+// ProjectFilenamesConfig is the convention secret files are named by. The
+// pattern is matched against the end of a path, so it may include
+// directories, e.g. templates/{env}/{name}-sealed.{ext}.
+type ProjectFilenamesConfig struct {
+	Pattern string `yaml:"pattern"`
+	// NameSuffix is appended to the name in the filename to give the Secret
+	// name, e.g. secret-password.production.yaml is password-secret.
+	NameSuffix string `yaml:"nameSuffix"`
+}
+
+var filenamePlaceholderRegexp = regexp.MustCompile(`\{[a-z]*\}`)
+
+func (c ProjectFilenamesConfig) validate() error {
+	if c.Pattern == "" {
+		return fmt.Errorf("filenames.pattern cannot be empty")
+	}
+	if strings.HasPrefix(c.Pattern, "/") {
+		return fmt.Errorf("filenames.pattern must be a relative path, got: %s", c.Pattern)
+	}
+	counts := map[string]int{}
+	for _, placeholder := range filenamePlaceholderRegexp.FindAllString(c.Pattern, -1) {
+		switch placeholder {
+		case FilenamePlaceholder_Name, FilenamePlaceholder_Environment, FilenamePlaceholder_Extension:
+			counts[placeholder]++
+		default:
+			return fmt.Errorf("filenames.pattern has unknown placeholder %s, expected %s, %s or %s",
+				placeholder, FilenamePlaceholder_Name, FilenamePlaceholder_Environment, FilenamePlaceholder_Extension)
+		}
+	}
+	for _, placeholder := range []string{FilenamePlaceholder_Name, FilenamePlaceholder_Environment} {
+		if counts[placeholder] != 1 {
+			return fmt.Errorf("filenames.pattern must contain %s exactly once, got: %s", placeholder, c.Pattern)
+		}
+	}
+	if counts[FilenamePlaceholder_Extension] > 1 {
+		return fmt.Errorf("filenames.pattern cannot contain %s more than once, got: %s",
+			FilenamePlaceholder_Extension, c.Pattern)
+	}
+	return nil
+}
+
+// regexp compiles the pattern, capturing the name, environment and extension.
+func (c ProjectFilenamesConfig) regexp() *regexp.Regexp {
+	expression := "^"
+	rest := c.Pattern
+	for {
+		location := filenamePlaceholderRegexp.FindStringIndex(rest)
+		if location == nil {
+			break
+		}
+		expression += regexp.QuoteMeta(rest[:location[0]])
+		switch rest[location[0]:location[1]] {
+		case FilenamePlaceholder_Name:
+			expression += `(?P<name>[^/.]+)`
+		case FilenamePlaceholder_Environment:
+			expression += `(?P<env>[a-z0-9-]+)`
+		case FilenamePlaceholder_Extension:
+			expression += `(?P<ext>yaml|yml)`
+		}
+		rest = rest[location[1]:]
+	}
+	expression += regexp.QuoteMeta(rest) + "$"
+	return regexp.MustCompile(expression)
+}
+
+// match splits a path into the directory the pattern is relative to and the
+// values of its placeholders.
+func (c ProjectFilenamesConfig) match(path string) (dir string, values map[string]string, err error) {
+	segments := strings.Count(c.Pattern, "/") + 1
+	candidate := filepath.ToSlash(filepath.Clean(path))
+	if strings.Count(candidate, "/")+1 < segments {
+		absolute, absErr := filepath.Abs(path)
+		if absErr == nil {
+			candidate = filepath.ToSlash(absolute)
+		}
+	}
+	split := strings.Split(candidate, "/")
+	if len(split) < segments {
+		err = c.mismatch(path)
+		return
+	}
+	dir = strings.Join(split[:len(split)-segments], "/")
+	re := c.regexp()
+	match := re.FindStringSubmatch(strings.Join(split[len(split)-segments:], "/"))
+	if match == nil {
+		err = c.mismatch(path)
+		return
+	}
+	values = map[string]string{}
+	for i, group := range re.SubexpNames() {
+		if group != "" {
+			values[group] = match[i]
+		}
+	}
+	if dir == "" && strings.HasPrefix(candidate, "/") {
+		dir = "/"
+	}
+	dir = filepath.FromSlash(dir)
+	return
+}
+
+func (c ProjectFilenamesConfig) mismatch(path string) error {
+	return fmt.Errorf("filename not in expected format '%s': %s\n\texpected a path like: %s",
+		c.Pattern, path, c.render("example", "production", "yaml"))
+}
+
+func (c ProjectFilenamesConfig) render(name string, environment string, extension string) string {
+	path := strings.Replace(c.Pattern, FilenamePlaceholder_Name, name, 1)
+	path = strings.Replace(path, FilenamePlaceholder_Environment, environment, 1)
+	path = strings.Replace(path, FilenamePlaceholder_Extension, extension, 1)
+	return filepath.FromSlash(path)
+}
+
+// NameAndEnvironment infers the Secret name and environment from the path of
+// a secret file.
+func (c ProjectFilenamesConfig) NameAndEnvironment(path string) (name string, environment string, err error) {
+	_, values, err := c.match(path)
+	if err != nil {
+		return
+	}
+	name = values["name"] + c.NameSuffix
+	environment = values["env"]
+	return
+}
+
+// Path suggests the path of the secret file for a Secret name and
+// environment, relative to dir.
+func (c ProjectFilenamesConfig) Path(dir string, name string, environment string) string {
+	return filepath.Join(dir, c.render(strings.TrimSuffix(name, c.NameSuffix), environment, "yaml"))
+}
+
+// Rename returns the path of the secret file for a different Secret name or
+// environment, keeping the directory and extension of the given path.
+func (c ProjectFilenamesConfig) Rename(path string, name string, environment string) (string, error) {
+	dir, values, err := c.match(path)
+	if err != nil {
+		return "", err
+	}
+	extension := values["ext"]
+	if extension == "" {
+		extension = "yaml"
+	}
+	return filepath.Join(dir, c.render(strings.TrimSuffix(name, c.NameSuffix), environment, extension)), nil
+}
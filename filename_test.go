@@ -0,0 +1,74 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectFilenamesConfig(t *testing.T) {
+	config := ProjectFilenamesConfig{Pattern: "templates/{env}/{name}-sealed.{ext}"}
+	tests := []struct {
+		filename    string
+		expectName  string
+		expectEnv   string
+		expectError bool
+	}{
+		{filename: "chart/templates/production/password-sealed.yaml", expectName: "password", expectEnv: "production"},
+		{filename: "templates/staging/db-sealed.yml", expectName: "db", expectEnv: "staging"},
+		{filename: "/abs/templates/staging/my-db-sealed.yaml", expectName: "my-db", expectEnv: "staging"},
+		{filename: "templates/staging/db.yaml", expectError: true},
+		{filename: "staging/db-sealed.yaml", expectError: true},
+		{filename: "templates/staging/db-sealed.json", expectError: true},
+	}
+	for _, test := range tests {
+		name, env, err := config.NameAndEnvironment(test.filename)
+		if (err != nil) != test.expectError {
+			t.Errorf("Expected error %t from filename '%s', got: %v", test.expectError, test.filename, err)
+		}
+		if name != test.expectName || env != test.expectEnv {
+			t.Errorf("Expected '%s' and '%s' from filename '%s', got '%s' and '%s'",
+				test.expectName, test.expectEnv, test.filename, name, env)
+		}
+	}
+}
+
+func TestProjectFilenamesConfigPaths(t *testing.T) {
+	config := ProjectFilenamesConfig{Pattern: "templates/{env}/{name}-sealed.{ext}"}
+	if path := config.Path("chart", "password", "production"); path != filepath.FromSlash("chart/templates/production/password-sealed.yaml") {
+		t.Errorf("Unexpected path: %s", path)
+	}
+	renamed, err := config.Rename("chart/templates/staging/db-sealed.yml", "db", "production")
+	if err != nil || renamed != filepath.FromSlash("chart/templates/production/db-sealed.yml") {
+		t.Errorf("Unexpected rename: %s, %v", renamed, err)
+	}
+
+	defaults := ProjectConfigDefault().Filenames
+	if path := defaults.Path("templates", "password-secret", "production"); path != filepath.FromSlash("templates/secret-password.production.yaml") {
+		t.Errorf("Unexpected default path: %s", path)
+	}
+	renamed, err = defaults.Rename("./templates/secret-password.production.yaml", "password-secret", "staging")
+	if err != nil || renamed != filepath.FromSlash("templates/secret-password.staging.yaml") {
+		t.Errorf("Unexpected default rename: %s, %v", renamed, err)
+	}
+}
+
+func TestProjectFilenamesConfigValidate(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		expectError bool
+	}{
+		{pattern: FilenamePattern_Default},
+		{pattern: "{env}/{name}.{ext}"},
+		{pattern: "", expectError: true},
+		{pattern: "/templates/{env}/{name}.yaml", expectError: true},
+		{pattern: "{name}.yaml", expectError: true},
+		{pattern: "{name}.{env}.{environment}.yaml", expectError: true},
+		{pattern: "{name}.{env}.{ext}.{ext}", expectError: true},
+	}
+	for _, test := range tests {
+		err := ProjectFilenamesConfig{Pattern: test.pattern}.validate()
+		if (err != nil) != test.expectError {
+			t.Errorf("Expected error %t for pattern '%s', got: %v", test.expectError, test.pattern, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyValueFlag is a repeatable flag of the form key=value, where each key
+// may only be given once.
+type keyValueFlag map[string]string
+
+func (f *keyValueFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	pairs := []string{}
+	for _, k := range sortedKeys(*f) {
+		pairs = append(pairs, k+"="+(*f)[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f *keyValueFlag) Set(value string) error {
+	split := strings.SplitN(value, "=", 2)
+	if len(split) != 2 || strings.TrimSpace(split[0]) == "" {
+		return fmt.Errorf("expected key=value but got '%s'", value)
+	}
+	if *f == nil {
+		*f = keyValueFlag{}
+	}
+	key := strings.TrimSpace(split[0])
+	if _, exists := (*f)[key]; exists {
+		return fmt.Errorf("key '%s' is given more than once", key)
+	}
+	(*f)[key] = split[1]
+	return nil
+}
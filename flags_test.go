@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestKeyValueFlag(t *testing.T) {
+	var f keyValueFlag
+	if err := f.Set("app=example"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := f.Set("example.com/url=https://example.com/?a=b"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := f.Set("=value"); err == nil {
+		t.Errorf("Expected error for empty key")
+	}
+	if err := f.Set("novalue"); err == nil {
+		t.Errorf("Expected error for missing '='")
+	}
+	if err := f.Set("app=other"); err == nil {
+		t.Errorf("Expected error for a key given twice")
+	}
+	expect := "app=example,example.com/url=https://example.com/?a=b"
+	if f.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, f.String())
+	}
+}
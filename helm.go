@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const HelmValuesPath_Default = ".Values.environment"
+
+// HelmConditionShape controls how a file gated on more than one environment
+// is expressed. A single environment is always written as an `eq`.
+const HelmConditionShape_Or = "or"
+const HelmConditionShape_Has = "has"
+
+var isValidValuesPath = regexp.MustCompile(`^\$?\.Values(\.[A-Za-z_][A-Za-z0-9_]*)+$`).MatchString
+
+type HelmCondition struct {
+	ValuesPath   string
+	Shape        string
+	Environments []string
+}
+
+func (c HelmCondition) String() string {
+	if len(c.Environments) == 1 {
+		return fmt.Sprintf("eq %s %s", c.ValuesPath, strconv.Quote(c.Environments[0]))
+	}
+	parts := []string{}
+	if c.Shape == HelmConditionShape_Has {
+		for _, environment := range c.Environments {
+			parts = append(parts, strconv.Quote(environment))
+		}
+		return fmt.Sprintf("has %s (list %s)", c.ValuesPath, strings.Join(parts, " "))
+	}
+	for _, environment := range c.Environments {
+		parts = append(parts, fmt.Sprintf("(eq %s %s)", c.ValuesPath, strconv.Quote(environment)))
+	}
+	return "or " + strings.Join(parts, " ")
+}
+
+func (c HelmCondition) FirstLine() string {
+	return fmt.Sprintf("{{- if %s }}", c)
+}
+
+// Matches reports whether the given environments are the same set as the
+// ones this condition is gated on.
+func (c HelmCondition) Matches(environments []string) bool {
+	if len(environments) != len(c.Environments) {
+		return false
+	}
+	expect := map[string]bool{}
+	for _, environment := range c.Environments {
+		expect[environment] = true
+	}
+	for _, environment := range environments {
+		if !expect[environment] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHelmCondition parses a condition expression (the part between `if` and
+// the closing braces) written in the configured shape, returning the
+// environments it is gated on.
+func parseHelmCondition(expression string, valuesPath string, shape string) (environments []string, err error) {
+	tokens, err := tokenizeHelmExpression(expression)
+	if err != nil {
+		return
+	}
+	p := helmExpressionParser{tokens: tokens, valuesPath: valuesPath}
+	switch {
+	case p.peek() == "eq":
+		var environment string
+		environment, err = p.eq()
+		environments = []string{environment}
+	case p.peek() == "or" && shape == HelmConditionShape_Or:
+		environments, err = p.or()
+	case p.peek() == "has" && shape == HelmConditionShape_Has:
+		environments, err = p.has()
+	default:
+		err = fmt.Errorf("condition must be in the form %s", HelmCondition{
+			ValuesPath:   valuesPath,
+			Shape:        shape,
+			Environments: []string{"a", "b"},
+		})
+	}
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected '%s' in condition", p.peek())
+	}
+	if err != nil {
+		environments = nil
+		err = fmt.Errorf("cannot parse Helm condition '%s': %s", expression, err)
+	}
+	return
+}
+
+func tokenizeHelmExpression(expression string) (tokens []string, err error) {
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			var quoted string
+			quoted, err = strconv.QuotedPrefix(expression[i:])
+			if err != nil {
+				err = fmt.Errorf("unterminated string at position %d", i+1)
+				return
+			}
+			tokens = append(tokens, quoted)
+			i += len(quoted)
+		default:
+			j := strings.IndexAny(expression[i:], " \t()\"")
+			if j < 0 {
+				j = len(expression) - i
+			}
+			tokens = append(tokens, expression[i:i+j])
+			i += j
+		}
+	}
+	return
+}
+
+type helmExpressionParser struct {
+	tokens     []string
+	pos        int
+	valuesPath string
+}
+
+func (p *helmExpressionParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *helmExpressionParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *helmExpressionParser) expect(token string) error {
+	if p.peek() != token {
+		return fmt.Errorf("expected '%s' but got '%s'", token, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *helmExpressionParser) valuesRef() error {
+	if p.peek() != p.valuesPath {
+		return fmt.Errorf("expected values path '%s' but got '%s'", p.valuesPath, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *helmExpressionParser) str() (string, error) {
+	token := p.peek()
+	if !strings.HasPrefix(token, `"`) {
+		return "", fmt.Errorf("expected quoted environment but got '%s'", token)
+	}
+	p.pos++
+	return strconv.Unquote(token)
+}
+
+func (p *helmExpressionParser) eq() (environment string, err error) {
+	if err = p.expect("eq"); err != nil {
+		return
+	}
+	if err = p.valuesRef(); err != nil {
+		return
+	}
+	return p.str()
+}
+
+func (p *helmExpressionParser) or() (environments []string, err error) {
+	if err = p.expect("or"); err != nil {
+		return
+	}
+	for !p.done() {
+		if err = p.expect("("); err != nil {
+			return
+		}
+		var environment string
+		if environment, err = p.eq(); err != nil {
+			return
+		}
+		if err = p.expect(")"); err != nil {
+			return
+		}
+		environments = append(environments, environment)
+	}
+	if len(environments) < 2 {
+		err = fmt.Errorf("'or' requires at least two environments")
+	}
+	return
+}
+
+func (p *helmExpressionParser) has() (environments []string, err error) {
+	if err = p.expect("has"); err != nil {
+		return
+	}
+	if err = p.valuesRef(); err != nil {
+		return
+	}
+	if err = p.expect("("); err != nil {
+		return
+	}
+	if err = p.expect("list"); err != nil {
+		return
+	}
+	for !p.done() && p.peek() != ")" {
+		var environment string
+		if environment, err = p.str(); err != nil {
+			return
+		}
+		environments = append(environments, environment)
+	}
+	if err = p.expect(")"); err != nil {
+		return
+	}
+	if len(environments) == 0 {
+		err = fmt.Errorf("'list' requires at least one environment")
+	}
+	return
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHelmConditionString(t *testing.T) {
+	tests := []struct {
+		condition HelmCondition
+		expect    string
+	}{
+		{
+			condition: HelmCondition{
+				ValuesPath:   HelmValuesPath_Default,
+				Shape:        HelmConditionShape_Or,
+				Environments: []string{"production"},
+			},
+			expect: `eq .Values.environment "production"`,
+		},
+		{
+			condition: HelmCondition{
+				ValuesPath:   ".Values.global.environment",
+				Shape:        HelmConditionShape_Or,
+				Environments: []string{"staging", "qa"},
+			},
+			expect: `or (eq .Values.global.environment "staging") (eq .Values.global.environment "qa")`,
+		},
+		{
+			condition: HelmCondition{
+				ValuesPath:   HelmValuesPath_Default,
+				Shape:        HelmConditionShape_Has,
+				Environments: []string{"staging", "qa"},
+			},
+			expect: `has .Values.environment (list "staging" "qa")`,
+		},
+		{
+			condition: HelmCondition{
+				ValuesPath:   HelmValuesPath_Default,
+				Shape:        HelmConditionShape_Has,
+				Environments: []string{"staging"},
+			},
+			expect: `eq .Values.environment "staging"`,
+		},
+	}
+	for _, test := range tests {
+		got := test.condition.String()
+		if got != test.expect {
+			t.Errorf("Expected:\n%s\nGot:\n%s", test.expect, got)
+		}
+		environments, err := parseHelmCondition(got, test.condition.ValuesPath, test.condition.Shape)
+		if err != nil {
+			t.Errorf("Unexpected error parsing '%s': %s", got, err)
+		}
+		if !reflect.DeepEqual(environments, test.condition.Environments) {
+			t.Errorf("Expected environments %v but got %v from '%s'", test.condition.Environments, environments, got)
+		}
+	}
+}
+
+func TestParseHelmCondition(t *testing.T) {
+	tests := []struct {
+		expression         string
+		valuesPath         string
+		shape              string
+		expectEnvironments []string
+		expectError        bool
+	}{
+		{
+			expression:         `eq .Values.environment "testing"`,
+			valuesPath:         HelmValuesPath_Default,
+			shape:              HelmConditionShape_Or,
+			expectEnvironments: []string{"testing"},
+		},
+		{
+			expression:         `or (eq .Values.global.environment "staging")  (eq .Values.global.environment "qa")`,
+			valuesPath:         ".Values.global.environment",
+			shape:              HelmConditionShape_Or,
+			expectEnvironments: []string{"staging", "qa"},
+		},
+		{
+			expression:  `eq .Values.global.environment "testing"`,
+			valuesPath:  HelmValuesPath_Default,
+			shape:       HelmConditionShape_Or,
+			expectError: true,
+		},
+		{
+			expression:  `has .Values.environment (list "staging" "qa")`,
+			valuesPath:  HelmValuesPath_Default,
+			shape:       HelmConditionShape_Or,
+			expectError: true,
+		},
+		{
+			expression:  `or (eq .Values.environment "staging")`,
+			valuesPath:  HelmValuesPath_Default,
+			shape:       HelmConditionShape_Or,
+			expectError: true,
+		},
+		{
+			expression:  `eq .Values.environment "testing" "extra"`,
+			valuesPath:  HelmValuesPath_Default,
+			shape:       HelmConditionShape_Or,
+			expectError: true,
+		},
+		{
+			expression:  `eq .Values.environment "testing`,
+			valuesPath:  HelmValuesPath_Default,
+			shape:       HelmConditionShape_Or,
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		environments, err := parseHelmCondition(test.expression, test.valuesPath, test.shape)
+		if err != nil && !test.expectError {
+			t.Errorf("Unexpected error '%s' from expression '%s'", err, test.expression)
+		}
+		if err == nil && test.expectError {
+			t.Errorf("Expected error from expression '%s'", test.expression)
+		}
+		if !reflect.DeepEqual(environments, test.expectEnvironments) {
+			t.Errorf("Expected environments %v but got %v from expression '%s'", test.expectEnvironments, environments, test.expression)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// kubernetesSecret is a v1 Secret manifest, as output by
+// `kubectl create secret ... -o yaml` or `kubectl get secret -o yaml`.
+type kubernetesSecret struct {
+	ApiVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Immutable  *bool             `yaml:"immutable"`
+	Data       map[string]string `yaml:"data"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+// Annotations set by kubectl which are not copied when importing. The last
+// applied configuration contains the Secret's data in plain text.
+var importIgnoredAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+type importOptions struct {
+	targetOptions
+	delete bool
+}
+
+func (o *importOptions) flags() []cli.Flag {
+	return append(o.targetOptions.flags("`namespace` the SealedSecret is scoped to (default the Secret's)"),
+		&cli.BoolFlag{Name: "delete", Destination: &o.delete, Usage: "overwrite and delete the plain text Secret file once sealed"},
+	)
+}
+
+// parseKubernetesSecret parses a v1 Secret manifest, in YAML or JSON.
+func parseKubernetesSecret(content []byte) (secret kubernetesSecret, err error) {
+	if err = yaml.Unmarshal(content, &secret); err != nil {
+		return secret, fmt.Errorf("not a valid Secret manifest: %s", err)
+	}
+	if secret.ApiVersion != "v1" || secret.Kind != "Secret" {
+		return secret, fmt.Errorf("expected a manifest with apiVersion v1 and kind Secret, got %s %s", secret.ApiVersion, secret.Kind)
+	}
+	return
+}
+
+// values returns the decoded values of the Secret, where stringData takes
+// precedence over data as it does when applied.
+func (s kubernetesSecret) values() (values map[string]string, err error) {
+	values = map[string]string{}
+	for key, encoded := range s.Data {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("data.%s is not valid base64: %s", key, err)
+		}
+		values[key] = string(decoded)
+	}
+	for key, value := range s.StringData {
+		values[key] = value
+	}
+	for _, key := range sortedKeys(values) {
+		if err := validateSecretKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("Secret has no data or stringData")
+	}
+	return
+}
+
+// importSealedSecret returns the SealedSecret for a Secret, without any
+// ciphertext. The Secret's labels and annotations are those of the unsealed
+// Secret, except for the annotations which set the scope of the SealedSecret.
+// With rename, as when --name is given, the Secret is imported under name
+// whatever its own name, which is safe as the values are sealed for name.
+func importSealedSecret(secret kubernetesSecret, name string, namespace string, environment string, rename bool) (sealedSecret SealedSecret, err error) {
+	if secret.Metadata.Name != "" && secret.Metadata.Name != name && !rename {
+		return sealedSecret, fmt.Errorf("Secret is named '%s' but the target expects '%s', give --name %s to import it under that name",
+			secret.Metadata.Name, name, name)
+	}
+	if namespace == "" {
+		namespace = secret.Metadata.Namespace
+	}
+	if namespace == "" {
+		return sealedSecret, fmt.Errorf("Secret has no namespace, give one with --namespace")
+	}
+	if err = validateNamespace(namespace); err != nil {
+		return
+	}
+	sealedSecret = SealedSecret{Environment: environment}
+	sealedSecret.Init(name, namespace)
+	sealedSecret.Spec.Template.Type = secret.Type
+	sealedSecret.Spec.Template.Immutable = secret.Immutable
+	sealedSecret.Spec.Template.Metadata.Labels = copyMap(secret.Metadata.Labels)
+	annotations := copyMap(secret.Metadata.Annotations)
+	for _, k := range importIgnoredAnnotations {
+		delete(annotations, k)
+	}
+	for _, k := range []string{SealedSecretAnnotation_NamespaceWide, SealedSecretAnnotation_ClusterWide} {
+		if v, exists := annotations[k]; exists {
+			if sealedSecret.Metadata.Annotations == nil {
+				sealedSecret.Metadata.Annotations = map[string]string{}
+			}
+			sealedSecret.Metadata.Annotations[k] = v
+			delete(annotations, k)
+		}
+	}
+	if len(annotations) > 0 {
+		sealedSecret.Spec.Template.Metadata.Annotations = annotations
+	}
+	return
+}
+
+// secureDelete overwrites a file with random bytes before removing it. On
+// copy-on-write or journaling filesystems and SSDs the original blocks may
+// survive, so this only reduces the chance of the content being recovered.
+func secureDelete(filename string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		_, err = io.CopyN(file, rand.Reader, info.Size())
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
+
+func importSecret(secretFilename string, filename string, options importOptions) {
+	if _, err := os.Stat(filename); err == nil {
+		fmt.Printf("Error: cannot import to %s as file already exists\n", filename)
+		os.Exit(1)
+	}
+	content, err := os.ReadFile(secretFilename)
+	if err != nil {
+		fmt.Printf("Cannot read file: %s\n", secretFilename)
+		os.Exit(1)
+	}
+	project, err := ProjectConfigLoad(filename)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	secretName, environment, err := options.resolve(filename, project)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	secret, err := parseKubernetesSecret(content)
+	var values map[string]string
+	if err == nil {
+		values, err = secret.values()
+	}
+	var sealedSecret SealedSecret
+	if err == nil {
+		sealedSecret, err = importSealedSecret(secret, secretName, options.namespace, environment, options.name != "")
+	}
+	if err != nil {
+		fmt.Printf("%s: %s\n", secretFilename, err)
+		os.Exit(1)
+	}
+
+	condition := project.HelmCondition(environment)
+	certFilename, err := loadConfig(condition.Environments...)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	sealedSecret.Spec.EncryptedData = sealValues(&sealedSecret, certFilename, values)
+	if project.RecordRotation {
+		cert, err := CertLoadFromFile(certFilename)
+		var certFingerprint string
+		if err == nil {
+			certFingerprint, _ = CertFingerprint(cert)
+		}
+		sealedSecret.RecordRotation(time.Now(), certFingerprint)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating new file: %s\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	out, err := sealedSecret.ToTemplate(file, condition, project.Output)
+	if err != nil {
+		fmt.Printf("error writing SealedSecret file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created SealedSecret file '%s' with keys %s and content:\n%s",
+		filename, strings.Join(sortedKeys(values), ", "), out.String())
+	if options.delete {
+		if err := secureDelete(secretFilename); err != nil {
+			fmt.Printf("error deleting %s: %s\n", secretFilename, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted %s\n", secretFilename)
+	}
+}
+
+func importCommand() *cli.Command {
+	options := importOptions{}
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "seal a plain text Secret manifest into a new SealedSecret file",
+		ArgsUsage: "(secret.yaml) (secret-example.environment.yaml)",
+		Flags:     options.flags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			importSecret(c.Args().Get(0), c.Args().Get(1), options)
+			return nil
+		},
+		BashComplete: completeCommand(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, func(c *cli.Context, args []string) {
+			if len(args) == 1 {
+				completeSecretFiles(c)
+			}
+		}),
+	}
+}
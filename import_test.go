@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testKubernetesSecret = "" +
+	"apiVersion: v1\n" +
+	"kind: Secret\n" +
+	"metadata:\n" +
+	"  name: db-secret\n" +
+	"  namespace: app\n" +
+	"  creationTimestamp: null\n" +
+	"  labels:\n" +
+	"    app: db\n" +
+	"  annotations:\n" +
+	"    kubectl.kubernetes.io/last-applied-configuration: '{\"data\":{\"PASSWORD\":\"c2VjcmV0\"}}'\n" +
+	"    sealedsecrets.bitnami.com/namespace-wide: \"true\"\n" +
+	"    owner: payments\n" +
+	"type: kubernetes.io/basic-auth\n" +
+	"data:\n" +
+	"  password: c2VjcmV0\n" +
+	"  username: YWRtaW4=\n" +
+	"stringData:\n" +
+	"  username: app\n"
+
+func TestParseKubernetesSecret(t *testing.T) {
+	secret, err := parseKubernetesSecret([]byte(testKubernetesSecret))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	values, err := secret.values()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := map[string]string{"password": "secret", "username": "app"}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("Expected %v, got %v", expect, values)
+	}
+
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"apiVersion: v1\nkind: ConfigMap\n", "expected a manifest with apiVersion v1 and kind Secret"},
+		{"apiVersion: v1\nkind: Secret\n", "Secret has no data or stringData"},
+		{"apiVersion: v1\nkind: Secret\ndata:\n  a: '!!'\n", "data.a is not valid base64"},
+		{"apiVersion: v1\nkind: Secret\nstringData:\n  bad key: x\n", "bad key"},
+		{"[", "not a valid Secret manifest"},
+	}
+	for _, test := range tests {
+		secret, err := parseKubernetesSecret([]byte(test.content))
+		if err == nil {
+			_, err = secret.values()
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Expected error containing '%s' for %q, got: %v", test.err, test.content, err)
+		}
+	}
+}
+
+func TestImportSealedSecret(t *testing.T) {
+	secret, err := parseKubernetesSecret([]byte(testKubernetesSecret))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sealedSecret, err := importSealedSecret(secret, "db-secret", "", "production", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := SealedSecret{Environment: "production"}
+	expect.Init("db-secret", "app")
+	expect.Metadata.Annotations = map[string]string{SealedSecretAnnotation_NamespaceWide: "true"}
+	expect.Spec.Template.Type = "kubernetes.io/basic-auth"
+	expect.Spec.Template.Metadata.Labels = map[string]string{"app": "db"}
+	expect.Spec.Template.Metadata.Annotations = map[string]string{"owner": "payments"}
+	if !reflect.DeepEqual(sealedSecret, expect) {
+		t.Errorf("Expected:\n%+v\nGot:\n%+v", expect, sealedSecret)
+	}
+
+	sealedSecret, err = importSealedSecret(secret, "db-secret", "other", "production", false)
+	if err != nil || sealedSecret.Metadata.Namespace != "other" || sealedSecret.Spec.Template.Metadata.Namespace != "other" {
+		t.Errorf("Expected --namespace to override the Secret's namespace, got %+v (%v)", sealedSecret.Metadata, err)
+	}
+	_, err = importSealedSecret(secret, "api-secret", "", "production", false)
+	if err == nil || !strings.Contains(err.Error(), "Secret is named 'db-secret' but the target expects 'api-secret'") {
+		t.Errorf("Expected error for name mismatch, got: %v", err)
+	}
+	sealedSecret, err = importSealedSecret(secret, "api-secret", "", "production", true)
+	if err != nil || sealedSecret.Metadata.Name != "api-secret" || sealedSecret.Spec.Template.Metadata.Name != "api-secret" {
+		t.Errorf("Expected --name to override the Secret's name, got %+v (%v)", sealedSecret.Metadata, err)
+	}
+	secret.Metadata.Namespace = ""
+	_, err = importSealedSecret(secret, "db-secret", "", "production", false)
+	if err == nil || !strings.Contains(err.Error(), "Secret has no namespace") {
+		t.Errorf("Expected error for missing namespace, got: %v", err)
+	}
+}
+
+func TestSecureDelete(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.yaml")
+	writeTestFile(t, filename, testKubernetesSecret)
+	if err := secureDelete(filename); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be deleted, got: %v", filename, err)
+	}
+	if err := secureDelete(filename); err == nil {
+		t.Errorf("Expected error deleting a missing file")
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// InspectResult describes the SealedSecrets of a file for an environment,
+// from what can be read without the controller's private key.
+type InspectResult struct {
+	File          string                `json:"file"`
+	Environment   string                `json:"environment"`
+	Environments  []string              `json:"environments"`
+	SealedSecrets []InspectSealedSecret `json:"sealedSecrets"`
+}
+
+type InspectSealedSecret struct {
+	Name            string          `json:"name"`
+	Namespace       string          `json:"namespace"`
+	Scope           string          `json:"scope"`
+	Keys            []InspectKey    `json:"keys"`
+	Template        InspectTemplate `json:"template"`
+	CertFingerprint string          `json:"certFingerprint,omitempty"`
+	LastRotated     string          `json:"lastRotated,omitempty"`
+	Warnings        []string        `json:"warnings"`
+}
+
+type InspectKey struct {
+	Key string `json:"key"`
+	// CiphertextLength is the length in bytes of the decoded ciphertext.
+	CiphertextLength int `json:"ciphertextLength"`
+}
+
+type InspectTemplate struct {
+	Type        string            `json:"type"`
+	Immutable   bool              `json:"immutable"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	DataKeys    []string          `json:"dataKeys,omitempty"`
+}
+
+// inspectSealedSecretFile parses a file and describes each SealedSecret in the
+// block for the environment, or only the one named if name is given.
+func inspectSealedSecretFile(filename string, target targetOptions) (result InspectResult, err error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		err = fmt.Errorf("cannot read file: %s", filename)
+		return
+	}
+	project, err := ProjectConfigLoad(filename)
+	if err != nil {
+		return
+	}
+	secretName, environment, err := target.resolve(filename, project)
+	if err != nil {
+		return
+	}
+	t, err := parseSealedSecretTemplate(filename, environment, string(content), project)
+	if err != nil {
+		return
+	}
+	sealedSecrets, err := t.SealedSecrets()
+	if err != nil {
+		return
+	}
+	result = InspectResult{
+		File:         filename,
+		Environment:  environment,
+		Environments: project.Environments(environment),
+	}
+	for _, sealedSecret := range sealedSecrets {
+		if target.name != "" && sealedSecret.Metadata.Name != target.name {
+			continue
+		}
+		expectedName := ""
+		if len(sealedSecrets) == 1 || target.name != "" {
+			expectedName = secretName
+		}
+		result.SealedSecrets = append(result.SealedSecrets, inspectSealedSecret(sealedSecret, expectedName))
+	}
+	if len(result.SealedSecrets) == 0 {
+		err = fmt.Errorf("template (%s) has no SealedSecret named '%s' for environment %s", filename, target.name, environment)
+	}
+	return
+}
+
+func inspectSealedSecret(sealedSecret SealedSecret, expectedName string) InspectSealedSecret {
+	template := sealedSecret.Spec.Template
+	inspected := InspectSealedSecret{
+		Name:            sealedSecret.Metadata.Name,
+		Namespace:       sealedSecret.Metadata.Namespace,
+		Scope:           sealedSecret.Scope(),
+		CertFingerprint: sealedSecret.Metadata.Annotations[SealedSecretAnnotation_CertFingerprint],
+		LastRotated:     sealedSecret.Metadata.Annotations[SealedSecretAnnotation_LastRotated],
+		Template: InspectTemplate{
+			Type:        template.Type,
+			Immutable:   template.Immutable != nil && *template.Immutable,
+			Labels:      template.Metadata.Labels,
+			Annotations: template.Metadata.Annotations,
+		},
+		Keys:     []InspectKey{},
+		Warnings: sealedSecretWarnings(sealedSecret, expectedName),
+	}
+	if inspected.Template.Type == "" {
+		inspected.Template.Type = SecretType_Opaque
+	}
+	if template.Data != nil {
+		inspected.Template.DataKeys = sortedKeys(*template.Data)
+	}
+	for _, key := range sortedKeys(sealedSecret.Spec.EncryptedData) {
+		ciphertext, _ := base64.StdEncoding.DecodeString(sealedSecret.Spec.EncryptedData[key])
+		inspected.Keys = append(inspected.Keys, InspectKey{Key: key, CiphertextLength: len(ciphertext)})
+	}
+	return inspected
+}
+
+// sealedSecretWarnings finds problems with a SealedSecret which can be seen
+// without decrypting it. The name is only checked if expectedName is given.
+func sealedSecretWarnings(sealedSecret SealedSecret, expectedName string) (warnings []string) {
+	warnings = []string{}
+	metadata := sealedSecret.Metadata
+	templateMetadata := sealedSecret.Spec.Template.Metadata
+	if expectedName != "" && metadata.Name != expectedName {
+		warnings = append(warnings, fmt.Sprintf("metadata.name is '%s' but the filename expects '%s'", metadata.Name, expectedName))
+	}
+	if templateMetadata.Name != "" && templateMetadata.Name != metadata.Name {
+		warnings = append(warnings, fmt.Sprintf("spec.template.metadata.name is '%s' but metadata.name is '%s'", templateMetadata.Name, metadata.Name))
+	}
+	if templateMetadata.Namespace != "" && templateMetadata.Namespace != metadata.Namespace {
+		warnings = append(warnings, fmt.Sprintf("spec.template.metadata.namespace is '%s' but metadata.namespace is '%s'", templateMetadata.Namespace, metadata.Namespace))
+	}
+	if metadata.Namespace == "" && sealedSecret.Scope() == SealedSecretScope_Strict {
+		warnings = append(warnings, "metadata.namespace is not set, so the SealedSecret is sealed to whichever namespace it is applied to")
+	}
+	if len(sealedSecret.Spec.EncryptedData) == 0 {
+		warnings = append(warnings, "spec.encryptedData has no keys")
+	}
+	for _, key := range sortedKeys(sealedSecret.Spec.EncryptedData) {
+		if warning := ciphertextWarning(key, sealedSecret.Spec.EncryptedData[key]); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return
+}
+
+func ciphertextWarning(key string, ciphertext string) string {
+	if ciphertext == "" {
+		return fmt.Sprintf("key '%s' is empty", key)
+	}
+	if _, err := base64.StdEncoding.DecodeString(ciphertext); err != nil {
+		return fmt.Sprintf("key '%s' is not valid base64 ciphertext", key)
+	}
+	return ""
+}
+
+func (r InspectResult) Write(w io.Writer, output string) error {
+	switch output {
+	case OutputFormat_Text:
+		fmt.Fprintf(w, "File:         %s\n", r.File)
+		fmt.Fprintf(w, "Environment:  %s", r.Environment)
+		if len(r.Environments) > 1 || r.Environments[0] != r.Environment {
+			fmt.Fprintf(w, " (%s)", strings.Join(r.Environments, ", "))
+		}
+		fmt.Fprintln(w)
+		for _, s := range r.SealedSecrets {
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "Name:         %s\n", s.Name)
+			fmt.Fprintf(w, "Namespace:    %s\n", s.Namespace)
+			fmt.Fprintf(w, "Scope:        %s\n", s.Scope)
+			fmt.Fprintf(w, "Type:         %s\n", s.Template.Type)
+			if s.Template.Immutable {
+				fmt.Fprintf(w, "Immutable:    true\n")
+			}
+			writeInspectMap(w, "Labels:", s.Template.Labels)
+			writeInspectMap(w, "Annotations:", s.Template.Annotations)
+			if len(s.Template.DataKeys) > 0 {
+				fmt.Fprintf(w, "Data:         %s\n", strings.Join(s.Template.DataKeys, ", "))
+			}
+			certFingerprint := s.CertFingerprint
+			if certFingerprint == "" {
+				certFingerprint = "not recorded"
+			}
+			fmt.Fprintf(w, "Cert:         %s\n", certFingerprint)
+			if s.LastRotated != "" {
+				fmt.Fprintf(w, "Last rotated: %s\n", s.LastRotated)
+			}
+			fmt.Fprintf(w, "Keys:\n")
+			for _, key := range s.Keys {
+				fmt.Fprintf(w, "  %s (%d bytes)\n", key.Key, key.CiphertextLength)
+			}
+			if len(s.Warnings) > 0 {
+				fmt.Fprintf(w, "Warnings:\n")
+				for _, warning := range s.Warnings {
+					fmt.Fprintf(w, "  %s\n", warning)
+				}
+			}
+		}
+		return nil
+	case OutputFormat_JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+	return checkOutputFormat(output, OutputFormat_Text, OutputFormat_JSON)
+}
+
+func writeInspectMap(w io.Writer, label string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s\n", label)
+	for _, k := range sortedKeys(m) {
+		fmt.Fprintf(w, "  %s=%s\n", k, m[k])
+	}
+}
+
+func inspectCommand() *cli.Command {
+	target := targetOptions{}
+	var output string
+	formats := []string{OutputFormat_Text, OutputFormat_JSON}
+	return &cli.Command{
+		Name:      "inspect",
+		Usage:     "show what a SealedSecret file contains, without decrypting it",
+		ArgsUsage: "(secret-example.environment.yaml)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "name", Destination: &target.name, Usage: "only show the SealedSecret named `name`"},
+			&cli.StringFlag{Name: "env", Destination: &target.environment, Usage: "`environment` (default taken from the filename)"},
+			outputFlag(&output, formats...),
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			if err := checkOutputFormat(output, formats...); err != nil {
+				return err
+			}
+			result, err := inspectSealedSecretFile(c.Args().First(), target)
+			if err != nil {
+				return err
+			}
+			return result.Write(c.App.Writer, output)
+		},
+		BashComplete: completeCommand(completeOutputFormats(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, formats...), func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				completeSecretFiles(c)
+			}
+		}),
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspectSealedSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "secret-db.production.yaml")
+	writeTestFile(t, filename, testMultiTemplate)
+
+	result, err := inspectSealedSecretFile(filename, targetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(result.SealedSecrets) != 2 {
+		t.Fatalf("Expected 2 SealedSecrets, got %+v", result.SealedSecrets)
+	}
+	db := result.SealedSecrets[0]
+	if db.Name != "db-secret" || db.Namespace != "example" || db.Scope != SealedSecretScope_Strict || db.Template.Type != SecretType_Opaque {
+		t.Errorf("Unexpected result: %+v", db)
+	}
+	if !reflect.DeepEqual(db.Keys, []InspectKey{{Key: "PASSWORD", CiphertextLength: 10}}) {
+		t.Errorf("Unexpected keys: %+v", db.Keys)
+	}
+	if len(db.Warnings) != 0 {
+		t.Errorf("Unexpected warnings: %v", db.Warnings)
+	}
+
+	result, err = inspectSealedSecretFile(filename, targetOptions{name: "api-secret"})
+	if err != nil || len(result.SealedSecrets) != 1 || result.SealedSecrets[0].Name != "api-secret" {
+		t.Errorf("Expected only api-secret, got %+v (%v)", result.SealedSecrets, err)
+	}
+	if _, err = inspectSealedSecretFile(filename, targetOptions{name: "missing-secret"}); err == nil {
+		t.Errorf("Expected error for a missing name")
+	}
+
+	out := &bytes.Buffer{}
+	if err = result.Write(out, OutputFormat_Text); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, expect := range []string{"Name:         api-secret\n", "Cert:         not recorded\n", "  TOKEN (11 bytes)\n", "Warnings:\n  key 'TOKEN' is not valid base64 ciphertext\n"} {
+		if !strings.Contains(out.String(), expect) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expect, out.String())
+		}
+	}
+}
+
+func TestSealedSecretWarnings(t *testing.T) {
+	sealedSecret := SealedSecret{}
+	sealedSecret.Init("db-secret", "example")
+	sealedSecret.Spec.Template.Metadata.Namespace = "other"
+	sealedSecret.Spec.EncryptedData = map[string]string{"EMPTY": "", "INVALID": "not base64!", "VALID": "AgA="}
+
+	got := sealedSecretWarnings(sealedSecret, "api-secret")
+	expect := []string{
+		"metadata.name is 'db-secret' but the filename expects 'api-secret'",
+		"spec.template.metadata.namespace is 'other' but metadata.namespace is 'example'",
+		"key 'EMPTY' is empty",
+		"key 'INVALID' is not valid base64 ciphertext",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expect, got)
+	}
+	if got := sealedSecretWarnings(sealedSecret, ""); len(got) != 3 {
+		t.Errorf("Expected the name not to be checked, got %v", got)
+	}
+}
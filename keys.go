@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+type keyOptions struct {
+	targetOptions
+	reformat bool
+}
+
+func (o *keyOptions) flags() []cli.Flag {
+	return append(o.targetOptions.flags("expected `namespace` of the SealedSecret"),
+		&cli.BoolFlag{Name: "reformat", Destination: &o.reformat, Usage: "re-indent the SealedSecret per the project output settings"},
+	)
+}
+
+// checkNewKeys checks keys are valid and neither already in existing nor
+// given twice.
+func checkNewKeys(existing []string, keys []string) error {
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if err := validateSecretKey(key); err != nil {
+			return err
+		}
+		if contains(existing, key) {
+			return fmt.Errorf("key '%s' already exists in spec.encryptedData", key)
+		}
+		if seen[key] {
+			return fmt.Errorf("key '%s' is given more than once", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// checkTemplateDataKeys checks the template data of the selected document
+// only references keys which will remain once the change is made.
+func (t *SealedSecretTemplate) checkTemplateDataKeys(keys []string) error {
+	sealedSecret, err := t.SealedSecret()
+	if err != nil || sealedSecret.Spec.Template.Data == nil {
+		return err
+	}
+	err = validateTemplateData(*sealedSecret.Spec.Template.Data, keys)
+	if err != nil {
+		return fmt.Errorf("%s, update it with rotate --template-data first", err)
+	}
+	return nil
+}
+
+// RemoveKeys deletes keys from spec.encryptedData of the selected document,
+// leaving the other entries as they were.
+func (t *SealedSecretTemplate) RemoveKeys(keys []string) error {
+	existing := t.Document.Keys("spec", "encryptedData")
+	remaining := []string{}
+	for _, key := range existing {
+		if !contains(keys, key) {
+			remaining = append(remaining, key)
+		}
+	}
+	for _, key := range keys {
+		if !contains(existing, key) {
+			return fmt.Errorf("key '%s' is not in spec.encryptedData of %s", key, t.Filename)
+		}
+	}
+	if len(remaining) == 0 {
+		return fmt.Errorf("cannot remove every key from %s, delete the SealedSecret instead", t.Filename)
+	}
+	if err := t.checkTemplateDataKeys(remaining); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := t.Document.Delete([]string{"spec", "encryptedData"}, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameKey renames a key in spec.encryptedData of the selected document,
+// keeping its position. The ciphertext is bound to the key it was sealed for,
+// so it is replaced by the given ciphertext sealed for newKey.
+func (t *SealedSecretTemplate) RenameKey(key string, newKey string, ciphertext string) error {
+	path := []string{"spec", "encryptedData"}
+	err := t.Document.Rename(path, key, newKey)
+	if err == nil {
+		err = t.Document.Set(path, newKey, ciphertext)
+	}
+	return err
+}
+
+// checkRenameKey checks key can be renamed to newKey before any value is
+// prompted for.
+func (t *SealedSecretTemplate) checkRenameKey(key string, newKey string) error {
+	existing := t.Document.Keys("spec", "encryptedData")
+	if !contains(existing, key) {
+		return fmt.Errorf("key '%s' is not in spec.encryptedData of %s", key, t.Filename)
+	}
+	if err := checkNewKeys(existing, []string{newKey}); err != nil {
+		return err
+	}
+	renamed := []string{newKey}
+	for _, k := range existing {
+		if k != key {
+			renamed = append(renamed, k)
+		}
+	}
+	return t.checkTemplateDataKeys(renamed)
+}
+
+// sealKeys prompts for a value for each key and seals them, failing if any
+// is left blank.
+func sealKeys(sealedSecret *SealedSecret, project ProjectConfig, condition HelmCondition, keys []string) (encryptedData map[string]string, certFingerprint string) {
+	secrets := PromptSecrets{noFileDetection: project.DisableFileDetection}
+	for _, key := range keys {
+		secrets.InitKey(key)
+	}
+	encryptedData, certFingerprint = rotateAndNew(sealedSecret, condition, &secrets, nil, valueOptions{})
+	for _, key := range keys {
+		if _, ok := encryptedData[key]; !ok {
+			fmt.Printf("Error: no value entered for key '%s'\n", key)
+			os.Exit(1)
+		}
+	}
+	return
+}
+
+// writeKeyChange records the rotation if the project asks for it, applies
+// the output settings and writes the file.
+func writeKeyChange(file *os.File, t *SealedSecretTemplate, project ProjectConfig, certFingerprint string, reformat bool) {
+	var err error
+	if project.RecordRotation && certFingerprint != "" {
+		err = t.RecordRotation(time.Now(), certFingerprint)
+	}
+	if err == nil {
+		err = t.ApplyOutput(reformat)
+	}
+	if err == nil {
+		err = t.Write(file)
+	}
+	if err != nil {
+		fmt.Printf("error writing SealedSecret file %s: %s\n", t.Filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Updated SealedSecret file '%s' with content:\n%s", t.Filename, t.String())
+}
+
+func addKey(filename string, keys []string, options keyOptions) {
+	file, t, sealedSecret, project, condition := openSealedSecret(filename, options.targetOptions, "add keys to")
+	defer file.Close()
+	if err := checkNewKeys(t.Document.Keys("spec", "encryptedData"), keys); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	encryptedData, certFingerprint := sealKeys(&sealedSecret, project, condition, keys)
+	if err := t.SetEncryptedData(encryptedData); err != nil {
+		fmt.Printf("error writing SealedSecret file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+	writeKeyChange(file, t, project, certFingerprint, options.reformat)
+}
+
+func removeKey(filename string, keys []string, options keyOptions) {
+	file, t, _, project, _ := openSealedSecret(filename, options.targetOptions, "remove keys from")
+	defer file.Close()
+	if err := t.RemoveKeys(keys); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	writeKeyChange(file, t, project, "", options.reformat)
+}
+
+func renameKey(filename string, key string, newKey string, options keyOptions) {
+	file, t, sealedSecret, project, condition := openSealedSecret(filename, options.targetOptions, "rename a key in")
+	defer file.Close()
+	if err := t.checkRenameKey(key, newKey); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("The value of '%s' is sealed for that key, so enter it again for '%s'.\n", key, newKey)
+	encryptedData, certFingerprint := sealKeys(&sealedSecret, project, condition, []string{newKey})
+	if err := t.RenameKey(key, newKey, encryptedData[newKey]); err != nil {
+		fmt.Printf("error writing SealedSecret file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+	writeKeyChange(file, t, project, certFingerprint, options.reformat)
+}
+
+func addKeyCommand() *cli.Command {
+	options := keyOptions{}
+	return &cli.Command{
+		Name:      "add-key",
+		Usage:     "add keys to an existing SealedSecret, prompting for their values",
+		ArgsUsage: "(secret-example.environment.yaml) (key...)",
+		Flags:     options.flags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 2 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			addKey(c.Args().First(), c.Args().Tail(), options)
+			return nil
+		},
+		BashComplete: completeCommand(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				completeSecretFiles(c)
+			}
+		}),
+	}
+}
+
+func removeKeyCommand() *cli.Command {
+	options := keyOptions{}
+	return &cli.Command{
+		Name:      "remove-key",
+		Usage:     "remove keys from an existing SealedSecret",
+		ArgsUsage: "(secret-example.environment.yaml) (key...)",
+		Flags:     options.flags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 2 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			removeKey(c.Args().First(), c.Args().Tail(), options)
+			return nil
+		},
+		BashComplete: completeCommand(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				completeSecretFiles(c)
+			} else {
+				completeKeys(c, args[0], options.targetOptions)
+			}
+		}),
+	}
+}
+
+func renameKeyCommand() *cli.Command {
+	options := keyOptions{}
+	return &cli.Command{
+		Name:      "rename-key",
+		Usage:     "rename a key of an existing SealedSecret, prompting for its value again",
+		ArgsUsage: "(secret-example.environment.yaml) (key) (new-key)",
+		Flags:     options.flags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 3 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			renameKey(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), options)
+			return nil
+		},
+		BashComplete: completeCommand(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, func(c *cli.Context, args []string) {
+			switch len(args) {
+			case 0:
+				completeSecretFiles(c)
+			case 1:
+				completeKeys(c, args[0], options.targetOptions)
+			}
+		}),
+	}
+}
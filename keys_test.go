@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testKeysTemplate = "" +
+	"{{- if eq .Values.environment \"testing\" }}\n" +
+	"apiVersion: bitnami.com/v1alpha1\n" +
+	"kind: SealedSecret\n" +
+	"metadata:\n" +
+	"  name: example-secret\n" +
+	"  namespace: example\n" +
+	"spec:\n" +
+	"  encryptedData:\n" +
+	"    # database\n" +
+	"    PASSWORD: AgPassword==\n" +
+	"    TOKEN: AgToken==\n" +
+	"    USERNAME: AgUsername==\n" +
+	"  template:\n" +
+	"    data:\n" +
+	"      url: postgres://{{ .USERNAME }}@db/app\n" +
+	"    metadata:\n" +
+	"      name: example-secret\n" +
+	"      namespace: example\n" +
+	"{{- end }}\n"
+
+func TestCheckNewKeys(t *testing.T) {
+	existing := []string{"PASSWORD", "USERNAME"}
+	tests := []struct {
+		keys []string
+		err  string
+	}{
+		{[]string{"TOKEN"}, ""},
+		{[]string{"TOKEN", "api.key"}, ""},
+		{[]string{"PASSWORD"}, "key 'PASSWORD' already exists"},
+		{[]string{"TOKEN", "TOKEN"}, "key 'TOKEN' is given more than once"},
+		{[]string{"bad key"}, "bad key"},
+	}
+	for _, test := range tests {
+		err := checkNewKeys(existing, test.keys)
+		if test.err == "" && err != nil {
+			t.Errorf("Unexpected error for %v: %s", test.keys, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("Expected error containing '%s' for %v, got: %v", test.err, test.keys, err)
+		}
+	}
+}
+
+func TestSealedSecretTemplateRemoveKeys(t *testing.T) {
+	filename := "templates/secret-example.testing.yaml"
+	tests := []struct {
+		keys   []string
+		err    string
+		expect string
+	}{
+		{
+			keys:   []string{"TOKEN"},
+			expect: strings.Replace(testKeysTemplate, "    TOKEN: AgToken==\n", "", 1),
+		},
+		{
+			keys: []string{"PASSWORD", "TOKEN"},
+			expect: strings.Replace(testKeysTemplate,
+				"    PASSWORD: AgPassword==\n    TOKEN: AgToken==\n", "", 1),
+		},
+		{keys: []string{"MISSING"}, err: "key 'MISSING' is not in spec.encryptedData"},
+		{keys: []string{"USERNAME"}, err: "spec.template.data 'url' references key 'USERNAME'"},
+		{keys: []string{"PASSWORD", "TOKEN", "USERNAME"}, err: "cannot remove every key"},
+	}
+	for _, test := range tests {
+		tmpl, err := parseSealedSecretTemplate(filename, "testing", testKeysTemplate, ProjectConfigDefault())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		err = tmpl.RemoveKeys(test.keys)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Expected error containing '%s' for %v, got: %v", test.err, test.keys, err)
+			}
+			if tmpl.String() != testKeysTemplate {
+				t.Errorf("Expected template to be unchanged for %v, got:\n%s", test.keys, tmpl.String())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %v: %s", test.keys, err)
+		} else if tmpl.String() != test.expect {
+			t.Errorf("Expected:\n%s\nGot:\n%s", test.expect, tmpl.String())
+		}
+	}
+}
+
+func TestSealedSecretTemplateRenameKey(t *testing.T) {
+	filename := "templates/secret-example.testing.yaml"
+	tests := []struct {
+		key    string
+		newKey string
+		err    string
+	}{
+		{"PASSWORD", "DB_PASSWORD", ""},
+		{"MISSING", "OTHER", "key 'MISSING' is not in spec.encryptedData"},
+		{"PASSWORD", "TOKEN", "key 'TOKEN' already exists"},
+		{"PASSWORD", "bad key", "bad key"},
+		{"USERNAME", "DB_USERNAME", "references key 'USERNAME'"},
+	}
+	for _, test := range tests {
+		tmpl, err := parseSealedSecretTemplate(filename, "testing", testKeysTemplate, ProjectConfigDefault())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		err = tmpl.checkRenameKey(test.key, test.newKey)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Expected error containing '%s' renaming %s, got: %v", test.err, test.key, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error renaming %s: %s", test.key, err)
+		}
+		err = tmpl.RenameKey(test.key, test.newKey, "AgNewPassword==")
+		if err != nil {
+			t.Fatalf("Unexpected error renaming %s: %s", test.key, err)
+		}
+		expect := strings.Replace(testKeysTemplate, "    PASSWORD: AgPassword==\n", "    DB_PASSWORD: AgNewPassword==\n", 1)
+		if tmpl.String() != expect {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expect, tmpl.String())
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Severity of a lint finding. Only errors fail the lint command.
+const (
+	LintSeverity_Error   = "error"
+	LintSeverity_Warning = "warning"
+)
+
+const (
+	OutputFormat_SARIF = "sarif"
+	OutputFormat_JUnit = "junit"
+)
+
+// LintRule is a check made by the lint command.
+type LintRule struct {
+	ID          string
+	Description string
+}
+
+var (
+	LintRule_Parse             = LintRule{"parse", "file parses as SealedSecrets wrapped in Helm conditions"}
+	LintRule_Environment       = LintRule{"environment", "Helm condition is gated on the environment in the filename"}
+	LintRule_EnvironmentConfig = LintRule{"environment-config", "environment has a cert configured"}
+	LintRule_Name              = LintRule{"name", "metadata.name is a valid Secret name matching the filename"}
+	LintRule_Namespace         = LintRule{"namespace", "metadata.namespace is a valid namespace"}
+	LintRule_Metadata          = LintRule{"metadata", "spec.template.metadata agrees with metadata"}
+	LintRule_Key               = LintRule{"key", "keys are valid Secret data keys"}
+	LintRule_Ciphertext        = LintRule{"ciphertext", "ciphertext is present and valid base64"}
+)
+
+var lintRules = []LintRule{
+	LintRule_Parse, LintRule_Environment, LintRule_EnvironmentConfig, LintRule_Name,
+	LintRule_Namespace, LintRule_Metadata, LintRule_Key, LintRule_Ciphertext,
+}
+
+type LintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type LintResult struct {
+	Files    []string      `json:"files"`
+	Findings []LintFinding `json:"findings"`
+}
+
+func (r LintResult) Errors() (count int) {
+	for _, finding := range r.Findings {
+		if finding.Severity == LintSeverity_Error {
+			count++
+		}
+	}
+	return
+}
+
+type lintOptions struct {
+	skipConfig bool
+	output     string
+}
+
+// lintSealedSecrets checks every secret file under dir.
+func lintSealedSecrets(dir string, options lintOptions) LintResult {
+	result := LintResult{Files: []string{}, Findings: []LintFinding{}}
+	var configured map[string]map[string]string
+	if !options.skipConfig {
+		configured = lintConfiguredEnvironments()
+	}
+	for _, filename := range secretFiles(dir) {
+		result.Files = append(result.Files, filename)
+		result.Findings = append(result.Findings, lintFile(filename, configured)...)
+	}
+	return result
+}
+
+func lintConfiguredEnvironments() map[string]map[string]string {
+	configDoc := ConfigDoc{}
+	configFile, err := ConfigFileDefaultPath("")
+	if err == nil && configDoc.Exists(configFile) {
+		configDoc.Load(configFile)
+	}
+	if configDoc.Environments == nil {
+		return map[string]map[string]string{}
+	}
+	return configDoc.Environments
+}
+
+func lintFile(filename string, configured map[string]map[string]string) (findings []LintFinding) {
+	add := func(line int, rule LintRule, severity string, message string) {
+		findings = append(findings, LintFinding{File: filename, Line: line, Rule: rule.ID, Severity: severity, Message: message})
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		add(0, LintRule_Parse, LintSeverity_Error, fmt.Sprintf("cannot read file: %s", err))
+		return
+	}
+	project, err := ProjectConfigLoad(filename)
+	if err != nil {
+		add(0, LintRule_Parse, LintSeverity_Error, err.Error())
+		return
+	}
+	secretName, environment, err := project.Filenames.NameAndEnvironment(filename)
+	if err != nil {
+		add(0, LintRule_Name, LintSeverity_Error, err.Error())
+		return
+	}
+	t, err := parseTemplateFile(filename, environment, string(content), project)
+	if err != nil {
+		add(lintErrorLine(err), LintRule_Parse, LintSeverity_Error, lintErrorMessage(err))
+		return
+	}
+	if _, err = parseSealedSecretTemplate(filename, environment, string(content), project); err != nil {
+		add(lintErrorLine(err), LintRule_Environment, LintSeverity_Error, lintErrorMessage(err))
+	}
+	target := t.Block(project.Environments(environment))
+	for _, block := range t.Blocks {
+		if configured != nil {
+			for _, env := range block.Environments {
+				if configured[env]["cert"] == "" {
+					add(block.Line, LintRule_EnvironmentConfig, LintSeverity_Error,
+						fmt.Sprintf("environment '%s' has no cert configured, run: kubesealplus config %s cert (your-cert-file)", env, env))
+				}
+			}
+		}
+		for i, document := range block.Documents {
+			line := func(path []string, key string) int {
+				if keyLine := document.Line(path, key); keyLine > 0 {
+					return block.DocumentLines[i] + keyLine - 1
+				}
+				return block.DocumentLines[i]
+			}
+			sealedSecret, err := decodeSealedSecret(document)
+			if err != nil {
+				add(block.DocumentLines[i], LintRule_Parse, LintSeverity_Error, fmt.Sprintf("not a valid SealedSecret: %s", err))
+				continue
+			}
+			metadata := sealedSecret.Metadata
+			templateMetadata := sealedSecret.Spec.Template.Metadata
+			if err := validateSecretName(metadata.Name); err != nil {
+				add(line([]string{"metadata"}, "name"), LintRule_Name, LintSeverity_Error, firstLine(err.Error()))
+			} else if block == target && len(block.Documents) == 1 && metadata.Name != secretName {
+				add(line([]string{"metadata"}, "name"), LintRule_Name, LintSeverity_Error,
+					fmt.Sprintf("metadata.name is '%s' but the filename expects '%s'", metadata.Name, secretName))
+			}
+			if metadata.Namespace != "" {
+				if err := validateNamespace(metadata.Namespace); err != nil {
+					add(line([]string{"metadata"}, "namespace"), LintRule_Namespace, LintSeverity_Error, firstLine(err.Error()))
+				}
+			} else if sealedSecret.Scope() == SealedSecretScope_Strict {
+				add(line(nil, "metadata"), LintRule_Namespace, LintSeverity_Warning,
+					"metadata.namespace is not set, so the SealedSecret is sealed to whichever namespace it is applied to")
+			}
+			if templateMetadata.Name != "" && templateMetadata.Name != metadata.Name {
+				add(line([]string{"spec", "template", "metadata"}, "name"), LintRule_Metadata, LintSeverity_Error,
+					fmt.Sprintf("spec.template.metadata.name is '%s' but metadata.name is '%s'", templateMetadata.Name, metadata.Name))
+			}
+			if templateMetadata.Namespace != "" && templateMetadata.Namespace != metadata.Namespace {
+				add(line([]string{"spec", "template", "metadata"}, "namespace"), LintRule_Metadata, LintSeverity_Error,
+					fmt.Sprintf("spec.template.metadata.namespace is '%s' but metadata.namespace is '%s'", templateMetadata.Namespace, metadata.Namespace))
+			}
+			encryptedData := []string{"spec", "encryptedData"}
+			if len(sealedSecret.Spec.EncryptedData) == 0 {
+				add(line([]string{"spec"}, "encryptedData"), LintRule_Ciphertext, LintSeverity_Warning, "spec.encryptedData has no keys")
+			}
+			for _, key := range document.Keys(encryptedData...) {
+				if err := validateSecretKey(key); err != nil {
+					add(line(encryptedData, key), LintRule_Key, LintSeverity_Error, err.Error())
+				}
+			}
+			for _, key := range document.Keys(encryptedData...) {
+				if warning := ciphertextWarning(key, sealedSecret.Spec.EncryptedData[key]); warning != "" {
+					add(line(encryptedData, key), LintRule_Ciphertext, LintSeverity_Warning, warning)
+				}
+			}
+			templateData := []string{"spec", "template", "data"}
+			for _, key := range document.Keys(templateData...) {
+				if err := validateSecretKey(key); err != nil {
+					add(line(templateData, key), LintRule_Key, LintSeverity_Error, err.Error())
+				}
+			}
+		}
+	}
+	return
+}
+
+func lintErrorLine(err error) int {
+	if templateErr, ok := err.(*TemplateError); ok {
+		return templateErr.Line
+	}
+	return 0
+}
+
+// lintErrorMessage is the message of an error without the location, which
+// findings hold separately.
+func lintErrorMessage(err error) string {
+	if templateErr, ok := err.(*TemplateError); ok {
+		message := firstLine(templateErr.Message)
+		if templateErr.Suggestion != "" {
+			message += " (suggestion: " + templateErr.Suggestion + ")"
+		}
+		return message
+	}
+	return err.Error()
+}
+
+func firstLine(s string) string {
+	return strings.SplitN(s, "\n", 2)[0]
+}
+
+func (r LintResult) Write(w io.Writer, output string) error {
+	switch output {
+	case OutputFormat_Text:
+		for _, finding := range r.Findings {
+			location := finding.File
+			if finding.Line > 0 {
+				location = fmt.Sprintf("%s:%d", finding.File, finding.Line)
+			}
+			fmt.Fprintf(w, "%s: %s: %s [%s]\n", location, finding.Severity, finding.Message, finding.Rule)
+		}
+		fmt.Fprintf(w, "%d files checked, %d errors, %d warnings\n",
+			len(r.Files), r.Errors(), len(r.Findings)-r.Errors())
+		return nil
+	case OutputFormat_JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case OutputFormat_SARIF:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r.sarif())
+	case OutputFormat_JUnit:
+		fmt.Fprint(w, xml.Header)
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(r.junit()); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		return nil
+	}
+	return checkOutputFormat(output, OutputFormat_Text, OutputFormat_JSON, OutputFormat_SARIF, OutputFormat_JUnit)
+}
+
+// sarif returns the findings as a SARIF 2.1.0 log, as read by GitHub code
+// scanning among others.
+func (r LintResult) sarif() map[string]interface{} {
+	rules := []map[string]interface{}{}
+	for _, rule := range lintRules {
+		rules = append(rules, map[string]interface{}{
+			"id":               rule.ID,
+			"shortDescription": map[string]string{"text": rule.Description},
+		})
+	}
+	results := []map[string]interface{}{}
+	for _, finding := range r.Findings {
+		location := map[string]interface{}{
+			"artifactLocation": map[string]string{"uri": filepath.ToSlash(finding.File)},
+		}
+		if finding.Line > 0 {
+			location["region"] = map[string]int{"startLine": finding.Line}
+		}
+		results = append(results, map[string]interface{}{
+			"ruleId":    finding.Rule,
+			"level":     finding.Severity,
+			"message":   map[string]string{"text": finding.Message},
+			"locations": []map[string]interface{}{{"physicalLocation": location}},
+		})
+	}
+	return map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]interface{}{{
+			"tool": map[string]interface{}{
+				"driver": map[string]interface{}{
+					"name":           "kubesealplus",
+					"version":        version,
+					"informationUri": "https://github.com/ryan0x44/kubesealplus",
+					"rules":          rules,
+				},
+			},
+			"results": results,
+		}},
+	}
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junit returns the findings as a JUnit report with a test case per file,
+// failing if the file has any errors. Warnings are included as output.
+func (r LintResult) junit() junitTestSuites {
+	suite := junitTestSuite{Name: "kubesealplus lint", Tests: len(r.Files)}
+	for _, file := range r.Files {
+		testCase := junitTestCase{Name: file, Classname: "kubesealplus.lint"}
+		errors, warnings := []string{}, []string{}
+		for _, finding := range r.Findings {
+			if finding.File != file {
+				continue
+			}
+			text := fmt.Sprintf("line %d: %s [%s]", finding.Line, finding.Message, finding.Rule)
+			if finding.Severity == LintSeverity_Error {
+				errors = append(errors, text)
+			} else {
+				warnings = append(warnings, text)
+			}
+		}
+		if len(errors) > 0 {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d lint errors", len(errors)),
+				Text:    strings.Join(errors, "\n"),
+			}
+		}
+		testCase.SystemOut = strings.Join(warnings, "\n")
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	return junitTestSuites{Suites: []junitTestSuite{suite}}
+}
+
+func lintCommand() *cli.Command {
+	options := lintOptions{}
+	formats := []string{OutputFormat_Text, OutputFormat_JSON, OutputFormat_SARIF, OutputFormat_JUnit}
+	return &cli.Command{
+		Name:      "lint",
+		Usage:     "check the secret files under a directory, exiting with status 1 on any error",
+		ArgsUsage: "[dir]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "skip-config", Destination: &options.skipConfig, Usage: "do not check environments have a cert configured"},
+			outputFlag(&options.output, formats...),
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() > 1 {
+				return usageError(c)
+			}
+			if err := checkOutputFormat(options.output, formats...); err != nil {
+				return err
+			}
+			dir := "."
+			if c.NArg() == 1 {
+				dir = c.Args().First()
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return fmt.Errorf("not a directory: %s", dir)
+			}
+			result := lintSealedSecrets(dir, options)
+			if err := result.Write(c.App.Writer, options.output); err != nil {
+				return err
+			}
+			if result.Errors() > 0 {
+				return cli.Exit("", 1)
+			}
+			return nil
+		},
+		BashComplete: completeCommand(completeOutputFormats(nil, formats...), func(c *cli.Context, args []string) {}),
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintSealedSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "secret-db.production.yaml"), testMultiTemplate)
+	writeTestFile(t, filepath.Join(dir, "secret-api.production.yaml"), ""+
+		"{{- if eq .Values.environment \"prod\" }}\n"+
+		"apiVersion: bitnami.com/v1alpha1\n"+
+		"kind: SealedSecret\n"+
+		"metadata:\n"+
+		"  name: other-secret\n"+
+		"  namespace: Example\n"+
+		"spec:\n"+
+		"  encryptedData:\n"+
+		"    bad key: AgA=\n"+
+		"  template:\n"+
+		"    metadata:\n"+
+		"      name: api-secret\n"+
+		"{{- end }}\n")
+	writeTestFile(t, filepath.Join(dir, "secret-broken.production.yaml"), ""+
+		"{{- if eq .Values.environment \"production\" }}\n"+
+		"foo: [\n"+
+		"{{- end }}\n")
+
+	result := lintSealedSecrets(dir, lintOptions{skipConfig: true})
+	got := []string{}
+	for _, finding := range result.Findings {
+		got = append(got, fmt.Sprintf("%s:%d:%s:%s", filepath.Base(finding.File), finding.Line, finding.Rule, finding.Severity))
+	}
+	expect := []string{
+		"secret-api.production.yaml:1:environment:error",
+		"secret-api.production.yaml:6:namespace:error",
+		"secret-api.production.yaml:12:metadata:error",
+		"secret-api.production.yaml:9:key:error",
+		"secret-broken.production.yaml:2:parse:error",
+		"secret-db.production.yaml:18:ciphertext:warning",
+		"secret-db.production.yaml:27:ciphertext:warning",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+	if result.Errors() != 5 || len(result.Files) != 3 {
+		t.Errorf("Expected 5 errors in 3 files, got %d in %d", result.Errors(), len(result.Files))
+	}
+}
+
+func TestLintResultWrite(t *testing.T) {
+	result := LintResult{
+		Files: []string{"a/secret-db.production.yaml", "a/secret-api.production.yaml"},
+		Findings: []LintFinding{
+			{File: "a/secret-db.production.yaml", Line: 5, Rule: LintRule_Name.ID, Severity: LintSeverity_Error, Message: "bad name"},
+			{File: "a/secret-api.production.yaml", Line: 9, Rule: LintRule_Ciphertext.ID, Severity: LintSeverity_Warning, Message: "empty"},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	if err := result.Write(out, OutputFormat_Text); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := "a/secret-db.production.yaml:5: error: bad name [name]\n" +
+		"a/secret-api.production.yaml:9: warning: empty [ciphertext]\n" +
+		"2 files checked, 1 errors, 1 warnings\n"
+	if out.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, out.String())
+	}
+
+	out.Reset()
+	if err := result.Write(out, OutputFormat_SARIF); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &sarif); err != nil {
+		t.Fatalf("Invalid SARIF JSON: %s", err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Results) != 2 ||
+		sarif.Runs[0].Results[0].RuleID != "name" || sarif.Runs[0].Results[1].Level != "warning" {
+		t.Errorf("Unexpected SARIF:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := result.Write(out, OutputFormat_JUnit); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var junit junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &junit); err != nil {
+		t.Fatalf("Invalid JUnit XML: %s", err)
+	}
+	suite := junit.Suites[0]
+	if suite.Tests != 2 || suite.Failures != 1 || suite.Cases[0].Failure == nil || suite.Cases[1].Failure != nil {
+		t.Errorf("Unexpected JUnit:\n%s", out.String())
+	}
+}
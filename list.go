@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+// SecretListEntry is a SealedSecret found by the list command.
+type SecretListEntry struct {
+	File        string   `json:"file"`
+	Name        string   `json:"name"`
+	Namespace   string   `json:"namespace"`
+	Environment string   `json:"environment"`
+	Scope       string   `json:"scope"`
+	Keys        []string `json:"keys"`
+	LastRotated string   `json:"lastRotated,omitempty"`
+}
+
+type listOptions struct {
+	environment string
+	namespace   string
+	output      string
+}
+
+// listSealedSecrets finds every SealedSecret in the secret files under dir,
+// along with the files which could not be parsed.
+func listSealedSecrets(dir string, options listOptions) (entries []SecretListEntry, errs []error) {
+	for _, filename := range secretFiles(dir) {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot read file: %s", filename))
+			continue
+		}
+		project, err := ProjectConfigLoad(filename)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_, environment, err := project.Filenames.NameAndEnvironment(filename)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if options.environment != "" && options.environment != environment &&
+			!contains(project.Environments(environment), options.environment) {
+			continue
+		}
+		t, err := parseSealedSecretTemplate(filename, environment, string(content), project)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sealedSecrets, err := t.SealedSecrets()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, sealedSecret := range sealedSecrets {
+			if options.namespace != "" && options.namespace != sealedSecret.Metadata.Namespace {
+				continue
+			}
+			entries = append(entries, SecretListEntry{
+				File:        filename,
+				Name:        sealedSecret.Metadata.Name,
+				Namespace:   sealedSecret.Metadata.Namespace,
+				Environment: environment,
+				Scope:       sealedSecret.Scope(),
+				Keys:        sortedKeys(sealedSecret.Spec.EncryptedData),
+				LastRotated: sealedSecret.Metadata.Annotations[SealedSecretAnnotation_LastRotated],
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].File < entries[j].File
+	})
+	return
+}
+
+func writeSecretList(w io.Writer, entries []SecretListEntry, output string) error {
+	header := []string{"FILE", "NAME", "NAMESPACE", "ENVIRONMENT", "SCOPE", "KEYS", "LAST ROTATED"}
+	row := func(entry SecretListEntry) []string {
+		return []string{entry.File, entry.Name, entry.Namespace, entry.Environment, entry.Scope,
+			strings.Join(entry.Keys, ","), entry.LastRotated}
+	}
+	switch output {
+	case OutputFormat_Text:
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, strings.Join(header, "\t"))
+		for _, entry := range entries {
+			fmt.Fprintln(table, strings.Join(row(entry), "\t"))
+		}
+		return table.Flush()
+	case OutputFormat_JSON:
+		if entries == nil {
+			entries = []SecretListEntry{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case OutputFormat_CSV:
+		writer := csv.NewWriter(w)
+		for i := range header {
+			header[i] = strings.ToLower(strings.ReplaceAll(header[i], " ", "-"))
+		}
+		writer.Write(header)
+		for _, entry := range entries {
+			writer.Write(row(entry))
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return checkOutputFormat(output, OutputFormat_Text, OutputFormat_JSON, OutputFormat_CSV)
+}
+
+func listCommand() *cli.Command {
+	options := listOptions{}
+	formats := []string{OutputFormat_Text, OutputFormat_JSON, OutputFormat_CSV}
+	return &cli.Command{
+		Name:      "list",
+		Usage:     "list the SealedSecrets in the secret files under a directory",
+		ArgsUsage: "[dir]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "env", Destination: &options.environment, Usage: "only list SealedSecrets for `environment`"},
+			&cli.StringFlag{Name: "namespace", Destination: &options.namespace, Usage: "only list SealedSecrets in `namespace`"},
+			outputFlag(&options.output, formats...),
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() > 1 {
+				return usageError(c)
+			}
+			if err := checkOutputFormat(options.output, formats...); err != nil {
+				return err
+			}
+			dir := "."
+			if c.NArg() == 1 {
+				dir = c.Args().First()
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return fmt.Errorf("not a directory: %s", dir)
+			}
+			entries, errs := listSealedSecrets(dir, options)
+			for _, err := range errs {
+				fmt.Fprintf(c.App.ErrWriter, "warning: %s\n", err)
+			}
+			return writeSecretList(c.App.Writer, entries, options.output)
+		},
+		BashComplete: completeCommand(completeOutputFormats(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, formats...), func(c *cli.Context, args []string) {}),
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListSealedSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "templates", "secret-db.production.yaml"), testMultiTemplate)
+	writeTestFile(t, filepath.Join(dir, "templates", "secret-db.staging.yaml"), testMultiTemplate)
+	writeTestFile(t, filepath.Join(dir, "templates", "secret-cache.staging.yaml"), ""+
+		"{{- if eq .Values.environment \"staging\" }}\n"+
+		"apiVersion: bitnami.com/v1alpha1\n"+
+		"kind: SealedSecret\n"+
+		"metadata:\n"+
+		"  name: cache-secret\n"+
+		"  namespace: cache\n"+
+		"  annotations:\n"+
+		"    sealedsecrets.bitnami.com/cluster-wide: \"true\"\n"+
+		"    kubesealplus/last-rotated: \"2023-03-01T00:00:00Z\"\n"+
+		"spec:\n"+
+		"  encryptedData:\n"+
+		"    URL: AgCache==\n"+
+		"{{- end }}\n")
+	writeTestFile(t, filepath.Join(dir, "templates", "secret-broken.staging.yaml"), "broken\n")
+
+	entries, errs := listSealedSecrets(dir, listOptions{})
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error for the broken file, got %v", errs)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Environment+"/"+entry.Name)
+	}
+	expect := []string{"staging/cache-secret", "production/db-secret", "production/api-secret", "staging/db-secret"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("Expected %v, got %v", expect, names)
+	}
+	if entries[0].Scope != SealedSecretScope_ClusterWide || entries[0].LastRotated != "2023-03-01T00:00:00Z" {
+		t.Errorf("Unexpected scope or last rotated: %+v", entries[0])
+	}
+
+	entries, _ = listSealedSecrets(dir, listOptions{environment: "staging", namespace: "example"})
+	if len(entries) != 1 || entries[0].Name != "db-secret" || entries[0].Environment != "staging" {
+		t.Errorf("Expected only staging/db-secret, got %+v", entries)
+	}
+}
+
+func TestWriteSecretList(t *testing.T) {
+	entries := []SecretListEntry{{
+		File: "templates/secret-db.production.yaml", Name: "db-secret", Namespace: "example",
+		Environment: "production", Scope: SealedSecretScope_Strict, Keys: []string{"PASSWORD", "USER"},
+	}}
+
+	out := &bytes.Buffer{}
+	if err := writeSecretList(out, entries, OutputFormat_Text); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(out.String(), "FILE ") || !strings.Contains(out.String(), "PASSWORD,USER") {
+		t.Errorf("Unexpected table:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := writeSecretList(out, entries, OutputFormat_CSV); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := "file,name,namespace,environment,scope,keys,last-rotated\n" +
+		"templates/secret-db.production.yaml,db-secret,example,production,strict,\"PASSWORD,USER\",\n"
+	if out.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, out.String())
+	}
+
+	out.Reset()
+	if err := writeSecretList(out, entries, OutputFormat_JSON); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var got []SecretListEntry
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil || !reflect.DeepEqual(got, entries) {
+		t.Errorf("Expected JSON to round trip, got %+v (%v)", got, err)
+	}
+
+	if err := writeSecretList(out, entries, "yaml"); err == nil {
+		t.Errorf("Expected error for unknown output format")
+	}
+}
@@ -1,69 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
-)
-
-func nameAndEnvFromFilename(path string) (name string, environment string, err error) {
-	filename := filepath.Base(path)
+	"time"
 
-	if !strings.HasPrefix(filename, "secret-") {
-		err = fmt.Errorf("filename missing expected 'secret-' prefix: %s", filename)
-		return
-	}
-	if !strings.HasSuffix(filename, ".yaml") {
-		err = fmt.Errorf("filename is missing the expected .yaml extension: %s", filename)
-		return
-	}
-	trimmed := strings.TrimPrefix(filename, "templates/")
-	trimmed = strings.TrimPrefix(trimmed, "secret-")
-	trimmed = strings.TrimSuffix(trimmed, ".yaml")
-	split := strings.Split(trimmed, ".")
-	if len(split) != 2 {
-		err = fmt.Errorf("filename not in expected format (incorrect number of period characters): %s", filename)
-		return
-	}
-	name = split[0] + "-secret"
-	environment = split[1]
-	return
-}
+	"github.com/urfave/cli/v2"
+)
 
 func main() {
-	command := ""
-	if len(os.Args) >= 2 {
-		command = os.Args[1]
-	}
-	switch command {
-	case "new":
-		if len(os.Args) != 3 || len(os.Args[2]) == 0 {
-			fmt.Printf("Usage:\n\tkubesealplus new (secret-example.environment.yaml)\n")
-			os.Exit(1)
-		}
-		new(os.Args[2])
-	case "rotate":
-		if len(os.Args) != 3 || len(os.Args[2]) == 0 {
-			fmt.Printf("Usage:\n\tkubesealplus rotate (secret-example.environment.yaml)\n")
-			os.Exit(1)
-		}
-		rotate(os.Args[2])
-	case "config":
-		if len(os.Args) != 5 || len(os.Args[2]) == 0 || os.Args[3] != "cert" {
-			fmt.Printf("Usage:\n\tkubesealplus config (environment) cert (file path or URL)\n")
-			os.Exit(1)
-		}
-		configure(os.Args[2], os.Args[3], os.Args[4])
-	default:
-		fmt.Println("Usage: kubesealplus COMMAND")
-		fmt.Println("")
-		fmt.Println("Commands:")
-		fmt.Println("\tnew (secret-example.environment.yaml)")
-		fmt.Println("\trotate (secret-example.environment.yaml)")
-		fmt.Println("\tconfig (environment) cert (file path or URL)")
+	err := newApp().Run(os.Args)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
 	}
 }
 
@@ -108,7 +61,9 @@ func configure(environment string, configKey string, configValue string) {
 	fmt.Printf("Cert value '%s'\nfor environment '%s'\nsuccessfully saved to config file '%s'\n", configValue, environment, configFile)
 }
 
-func loadConfig(environment string) (certFilename string, err error) {
+// loadConfig loads the cert for the given environments. A file gated on more
+// than one environment is sealed once, so every environment must share a cert.
+func loadConfig(environments ...string) (certFilename string, err error) {
 	configFile, err := ConfigFileDefaultPath("")
 	if err != nil {
 		panic(err)
@@ -116,7 +71,7 @@ func loadConfig(environment string) (certFilename string, err error) {
 	configDoc := ConfigDoc{}
 	if !configDoc.Exists(configFile) {
 		err = fmt.Errorf("Config for environment '%s' not found. Run this:\n"+
-			"kubesealplus config %s cert (your-cert-file)", environment, environment)
+			"kubesealplus config %s cert (your-cert-file)", environments[0], environments[0])
 		return
 	}
 	err = configDoc.Load(configFile)
@@ -125,45 +80,241 @@ func loadConfig(environment string) (certFilename string, err error) {
 		return
 	}
 
-	certConfigValue := configDoc.Environments[environment]["cert"]
-	// TODO: implement caching of cert load.
-	// we probably only need to download it at most once per hour (or day?)
-	cert, err := CertLoad(certConfigValue)
+	var cert []byte
+	for i, environment := range environments {
+		certConfigValue := configDoc.Environments[environment]["cert"]
+		if certConfigValue == "" {
+			err = fmt.Errorf("Config for environment '%s' not found. Run this:\n"+
+				"kubesealplus config %s cert (your-cert-file)", environment, environment)
+			return
+		}
+		// TODO: implement caching of cert load.
+		// we probably only need to download it at most once per hour (or day?)
+		var envCert []byte
+		envCert, err = CertLoad(certConfigValue)
+		if err != nil {
+			err = fmt.Errorf("unable to load cert '%s':\n%s\n", certConfigValue, err)
+			return
+		}
+		if i > 0 && !bytes.Equal(cert, envCert) {
+			err = fmt.Errorf("environments '%s' and '%s' do not share the same cert, "+
+				"so cannot be sealed in a single file", environments[0], environment)
+			return
+		}
+		cert = envCert
+	}
+	certFilename, err = ConfigWriteCert(environments[0], cert)
 	if err != nil {
-		err = fmt.Errorf("unable to load cert '%s':\n%s\n", certConfigValue, err)
+		err = fmt.Errorf("Unable to write latest cert to disk:\n%s\n", err)
 		return
 	}
-	certFilename, err = ConfigWriteCert(environment, cert)
+	return
+}
+
+// targetOptions override the Secret name and environment otherwise taken
+// from the filename, and the namespace otherwise prompted for.
+type targetOptions struct {
+	name        string
+	environment string
+	namespace   string
+	// noPrompt is set when stdin is used for values, so which SealedSecret
+	// to work on can't be prompted for.
+	noPrompt bool
+}
+
+func (o *targetOptions) flags(namespaceUsage string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "name", Destination: &o.name, Usage: "Secret `name` (default taken from the filename)"},
+		&cli.StringFlag{Name: "env", Destination: &o.environment, Usage: "`environment` (default taken from the filename)"},
+		&cli.StringFlag{Name: "namespace", Destination: &o.namespace, Usage: namespaceUsage},
+	}
+}
+
+// resolve returns the Secret name and environment for a file, validating
+// them and any namespace given before anything is prompted for.
+func (o targetOptions) resolve(filename string, project ProjectConfig) (name string, environment string, err error) {
+	name, environment, err = project.Filenames.NameAndEnvironment(filename)
 	if err != nil {
-		err = fmt.Errorf("Unable to write latest cert to disk:\n%s\n", err)
+		if o.name == "" || o.environment == "" {
+			return
+		}
+		err = nil
+	}
+	if o.name != "" {
+		name = o.name
+	}
+	if o.environment != "" {
+		environment = o.environment
+	}
+	if !isValidEnv(environment) {
+		err = fmt.Errorf("invalid environment name: %s", environment)
+		return
+	}
+	if err = validateSecretName(name); err != nil {
 		return
 	}
+	if o.namespace != "" {
+		err = validateNamespace(o.namespace)
+	}
 	return
 }
 
-func new(filename string) {
+// check fails if the metadata of an existing SealedSecret disagrees with the
+// name and namespace expected for it.
+func (o targetOptions) check(filename string, sealedSecret SealedSecret, name string) error {
+	source := "the filename"
+	if o.name != "" {
+		source = "--name"
+	}
+	if sealedSecret.Metadata.Name != name {
+		return fmt.Errorf("SealedSecret in %s is named '%s' but %s expects '%s'",
+			filename, sealedSecret.Metadata.Name, source, name)
+	}
+	if o.namespace != "" && sealedSecret.Metadata.Namespace != o.namespace {
+		return fmt.Errorf("SealedSecret in %s has namespace '%s' but --namespace expects '%s'",
+			filename, sealedSecret.Metadata.Namespace, o.namespace)
+	}
+	return nil
+}
+
+type newOptions struct {
+	targetOptions
+	secretType          string
+	immutable           bool
+	labels              keyValueFlag
+	annotations         keyValueFlag
+	templateLabels      keyValueFlag
+	templateAnnotations keyValueFlag
+	templateData        string
+	append              bool
+	values              valueOptions
+}
+
+func (o *newOptions) flags() []cli.Flag {
+	return append(append(o.targetOptions.flags("`namespace` the SealedSecret is scoped to (default prompted for)"),
+		&cli.StringFlag{Name: "type", Destination: &o.secretType, Usage: "`type` of the unsealed Secret (default Opaque), or one of " +
+			strings.Join(secretTypeBuilderNames(), ", ") + " to be prompted for its fields"},
+		&cli.BoolFlag{Name: "immutable", Destination: &o.immutable, Usage: "mark the unsealed Secret as immutable"},
+		&cli.GenericFlag{Name: "label", Value: &o.labels, Usage: "label `key=value` for the SealedSecret (repeatable)"},
+		&cli.GenericFlag{Name: "annotation", Value: &o.annotations, Usage: "annotation `key=value` for the SealedSecret (repeatable)"},
+		&cli.GenericFlag{Name: "template-label", Value: &o.templateLabels, Usage: "label `key=value` for the unsealed Secret (repeatable)"},
+		&cli.GenericFlag{Name: "template-annotation", Value: &o.templateAnnotations, Usage: "annotation `key=value` for the unsealed Secret (repeatable)"},
+		&cli.StringFlag{Name: "template-data", Destination: &o.templateData, Usage: "YAML `file` of spec.template.data entries to set (null values remove an entry)"},
+		&cli.BoolFlag{Name: "append", Destination: &o.append, Usage: "add to an existing file, as another document in the environment's block or as a new 'else if' block"},
+	), o.values.flags()...)
+}
+
+// apply sets the metadata and template fields given by flags.
+func (o newOptions) apply(sealedSecret *SealedSecret) {
+	sealedSecret.Metadata.Labels = o.labels
+	sealedSecret.Metadata.Annotations = o.annotations
+	sealedSecret.Spec.Template.Metadata.Labels = o.templateLabels
+	sealedSecret.Spec.Template.Metadata.Annotations = o.templateAnnotations
+	sealedSecret.Spec.Template.Type = o.secretType
+	if o.immutable {
+		immutable := true
+		sealedSecret.Spec.Template.Immutable = &immutable
+	}
+}
+
+func new(filename string, options newOptions) {
 	fileInfo, err := os.Stat(filename)
-	if err == nil && fileInfo != nil {
-		fmt.Printf("Error: cannot create new file as file already exists\n\t%s\n", filename)
+	if err == nil && fileInfo != nil && !options.append {
+		fmt.Printf("Error: cannot create new file as file already exists (use --append to add to it)\n\t%s\n", filename)
 		os.Exit(1)
 	}
 
-	secretName, environment, err := nameAndEnvFromFilename(filename)
+	project, err := ProjectConfigLoad(filename)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		os.Exit(1)
 	}
-	secrets := PromptSecrets{}
-	namespace, err := secrets.Namespace(os.Stdin, os.Stdout)
+	secretName, environment, err := options.resolve(filename, project)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		os.Exit(1)
 	}
+	condition := project.HelmCondition(environment)
+	var existing *SealedSecretTemplate
+	if options.append && fileInfo != nil {
+		template, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Printf("Cannot read file: %s\n", filename)
+			os.Exit(1)
+		}
+		existing, err = parseTemplateFile(filename, environment, string(template), project)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		if contains(existing.Names(), secretName) {
+			fmt.Printf("Error: %s already contains a SealedSecret named '%s' for environment %s\n", filename, secretName, environment)
+			os.Exit(1)
+		}
+	}
+	var templateData map[string]*string
+	if options.templateData != "" {
+		templateData, err = loadTemplateData(options.templateData)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+	}
+	secrets := PromptSecrets{noFileDetection: project.DisableFileDetection}
+	var enter func(redo int) error
+	if builder, exists := secretTypeBuilderFor(options.secretType); exists {
+		if options.values.given() {
+			fmt.Printf("Error: values for --type %s are prompted for, so can't be read from a file\n", options.secretType)
+			os.Exit(1)
+		}
+		options.secretType = builder.Type
+		enter = func(redo int) error {
+			return secrets.Typed(builder, os.Stdin, os.Stdout)
+		}
+	}
+	namespace := options.namespace
+	if namespace == "" && options.values.fromStdin() {
+		fmt.Printf("Error: --namespace is required when reading values from stdin\n")
+		os.Exit(1)
+	}
+	if namespace == "" {
+		namespace, err = secrets.Namespace(os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+	}
 
 	sealedSecret := SealedSecret{Environment: environment}
 	sealedSecret.Init(secretName, namespace)
+	options.apply(&sealedSecret)
 
-	rotateAndNew(&sealedSecret, secrets)
+	var certFingerprint string
+	sealedSecret.Spec.EncryptedData, certFingerprint = rotateAndNew(&sealedSecret, condition, &secrets, enter, options.values)
+	if project.RecordRotation {
+		sealedSecret.RecordRotation(time.Now(), certFingerprint)
+	}
+	if data := mergeTemplateData(nil, templateData); data != nil {
+		err = validateTemplateData(data, sortedKeys(sealedSecret.Spec.EncryptedData))
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		sealedSecret.Spec.Template.Data = &data
+	}
+
+	if existing != nil {
+		err = existing.Append(sealedSecret)
+		if err == nil {
+			err = os.WriteFile(filename, []byte(existing.String()), fileInfo.Mode())
+		}
+		if err != nil {
+			fmt.Printf("error writing SealedSecret file %s: %s\n", filename, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Added SealedSecret to file '%s' with content:\n%s", filename, existing.String())
+		return
+	}
 
 	file, err := os.Create(filename)
 	if err != nil {
@@ -171,7 +322,7 @@ func new(filename string) {
 		os.Exit(1)
 	}
 	defer file.Close()
-	out, err := sealedSecret.ToTemplate(file, environment)
+	out, err := sealedSecret.ToTemplate(file, condition, project.Output)
 	if err != nil {
 		fmt.Printf("error writing SealedSecret file %s: %s\n", filename, err)
 		os.Exit(1)
@@ -179,55 +330,173 @@ func new(filename string) {
 	fmt.Printf("Created SealedSecret file '%s' with content:\n%s", filename, out.String())
 }
 
-func rotate(filename string) {
+type rotateOptions struct {
+	targetOptions
+	templateData string
+	reformat     bool
+	values       valueOptions
+}
+
+func (o *rotateOptions) flags() []cli.Flag {
+	return append(append(o.targetOptions.flags("expected `namespace` of the SealedSecret"),
+		&cli.StringFlag{Name: "template-data", Destination: &o.templateData, Usage: "YAML `file` of spec.template.data entries to set (null values remove an entry)"},
+		&cli.BoolFlag{Name: "reformat", Destination: &o.reformat, Usage: "re-indent the SealedSecret per the project output settings"},
+	), o.values.flags()...)
+}
+
+// openSealedSecret opens a SealedSecret file for editing and selects the
+// SealedSecret to work on, by --name or by prompting if the environment's
+// block has more than one. action describes what will be done to it.
+func openSealedSecret(filename string, target targetOptions, action string) (file *os.File, t *SealedSecretTemplate, sealedSecret SealedSecret, project ProjectConfig, condition HelmCondition) {
 	file, err := os.OpenFile(filename, os.O_RDWR, 0644)
 	if err != nil {
 		fmt.Printf("Cannot open file: %s\n", filename)
 		os.Exit(1)
 	}
-	defer file.Close()
 	template, err := io.ReadAll(file)
 	if err != nil {
 		fmt.Printf("Cannot read file: %s\n", filename)
 		os.Exit(1)
 	}
-	_, environment, err := nameAndEnvFromFilename(filename)
+	project, err = ProjectConfigLoad(filename)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		os.Exit(1)
 	}
-	sealedSecret, err := sealedSecretFromTemplate(filename, environment, string(template))
+	secretName, environment, err := target.resolve(filename, project)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		os.Exit(1)
 	}
-	secrets := PromptSecrets{}
-	for k := range sealedSecret.Spec.EncryptedData {
+	condition = project.HelmCondition(environment)
+	t, err = parseSealedSecretTemplate(filename, environment, string(template), project)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	checkName := t.Document != nil || target.name != ""
+	if t.Document == nil && target.name != "" {
+		err = t.Select(target.name)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+	}
+	if t.Document == nil && target.noPrompt {
+		fmt.Printf("Error: %s contains more than one SealedSecret for %s, choose which to %s with --name\n", filename, environment, action)
+		os.Exit(1)
+	}
+	if t.Document == nil {
+		names := t.Names()
+		choice, err := (&PromptSecrets{}).Choose(
+			fmt.Sprintf("%s contains more than one SealedSecret for %s, which do you want to %s?", filename, environment, action),
+			names, os.Stdin, os.Stdout,
+		)
+		if err == nil {
+			err = t.Select(names[choice])
+		}
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+	}
+	sealedSecret, err = t.SealedSecret()
+	if err == nil && !checkName {
+		secretName = sealedSecret.Metadata.Name
+	}
+	if err == nil {
+		err = target.check(filename, sealedSecret, secretName)
+	}
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	return
+}
+
+func rotate(filename string, options rotateOptions) {
+	options.noPrompt = options.values.fromStdin()
+	file, t, sealedSecret, project, condition := openSealedSecret(filename, options.targetOptions, "rotate")
+	defer file.Close()
+	var err error
+	var templateData map[string]*string
+	if options.templateData != "" {
+		templateData, err = loadTemplateData(options.templateData)
+		if err == nil {
+			var existing map[string]string
+			if sealedSecret.Spec.Template.Data != nil {
+				existing = *sealedSecret.Spec.Template.Data
+			}
+			err = validateTemplateData(
+				mergeTemplateData(existing, templateData),
+				t.Document.Keys("spec", "encryptedData"),
+			)
+		}
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+	}
+	secrets := PromptSecrets{noFileDetection: project.DisableFileDetection}
+	for _, k := range t.Document.Keys("spec", "encryptedData") {
 		secrets.InitKey(k)
 	}
-	rotateAndNew(&sealedSecret, secrets)
+	encryptedData, certFingerprint := rotateAndNew(&sealedSecret, condition, &secrets, nil, options.values)
 
-	out, err := sealedSecret.ToTemplate(file, environment)
+	err = t.SetEncryptedData(encryptedData)
+	if err == nil && project.RecordRotation && len(encryptedData) > 0 {
+		err = t.RecordRotation(time.Now(), certFingerprint)
+	}
+	if err == nil {
+		err = t.SetTemplateData(templateData)
+	}
+	if err == nil {
+		err = t.ApplyOutput(options.reformat)
+	}
+	if err == nil {
+		err = t.Write(file)
+	}
 	if err != nil {
 		fmt.Printf("error writing SealedSecret file %s: %s\n", filename, err)
 		os.Exit(1)
 	}
-	fmt.Printf("Updated SealedSecret file '%s' with content:\n%s", filename, out.String())
+	fmt.Printf("Updated SealedSecret file '%s' with content:\n%s", filename, t.String())
 }
 
-func rotateAndNew(sealedSecret *SealedSecret, secrets PromptSecrets) {
-	certFilename, err := loadConfig(sealedSecret.Environment)
+// rotateAndNew prompts for secret values and returns them sealed, keyed by
+// their key within spec.encryptedData, along with the fingerprint of the cert
+// they were sealed with. Keys left blank are not returned.
+// Values are read from the sources in values if given, otherwise entered via
+// enter if given, otherwise by key-value pairs (new) or per existing key
+// (rotate). Values read from sources are only reviewed if values.confirm.
+func rotateAndNew(sealedSecret *SealedSecret, condition HelmCondition, secrets *PromptSecrets, enter func(redo int) error, values valueOptions) (encryptedData map[string]string, certFingerprint string) {
+	certFilename, err := loadConfig(condition.Environments...)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		os.Exit(1)
 	}
+	if cert, err := CertLoadFromFile(certFilename); err == nil {
+		certFingerprint, _ = CertFingerprint(cert)
+	}
 
+	loaded := values.given()
+	if loaded {
+		if err := values.load(secrets, os.Stdin); err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+	}
 	redo := 0
-	for {
+	for !loaded || values.confirm {
 		var err error
-		if len(secrets.secrets) > 0 {
+		switch {
+		case loaded && redo == 0:
+			// Values read from a source are reviewed before any are re-entered.
+		case enter != nil:
+			err = enter(redo)
+		case len(secrets.secrets) > 0:
 			err = secrets.Update(redo, os.Stdin, os.Stdout)
-		} else {
+		default:
 			err = secrets.Enter(os.Stdin, os.Stdout)
 		}
 		if err != nil {
@@ -243,12 +512,30 @@ func rotateAndNew(sealedSecret *SealedSecret, secrets PromptSecrets) {
 			break
 		}
 	}
-	PromptClear(os.Stdout)
+	if !loaded || values.confirm {
+		PromptClear(os.Stdout)
+	}
+	if loaded {
+		set, unchanged := secrets.Keys()
+		fmt.Printf("Keys set: %s\n", strings.Join(set, ", "))
+		if len(unchanged) > 0 {
+			fmt.Printf("Keys unchanged: %s\n", strings.Join(unchanged, ", "))
+		}
+	}
 
-	newSecrets := secrets.ToValues()
+	return sealValues(sealedSecret, certFilename, secrets.ToValues()), certFingerprint
+}
+
+// sealValues seals values with kubeseal for the name, namespace and scope of
+// sealedSecret, returning the ciphertext keyed by their key.
+func sealValues(sealedSecret *SealedSecret, certFilename string, values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return map[string]string{}
+	}
 	secretYAML, err := createSecretYAML(
-		sealedSecret.Spec.Template.Metadata,
-		newSecrets,
+		sealedSecret.SealingMetadata(),
+		sealedSecret.Spec.Template.Type,
+		values,
 	)
 	if err != nil {
 		fmt.Printf("error creating Secret:\n%s\n", err)
@@ -260,15 +547,10 @@ func rotateAndNew(sealedSecret *SealedSecret, secrets PromptSecrets) {
 		fmt.Printf("error creating SealedSecret via kubeseal:\n%s\n", err)
 		os.Exit(1)
 	}
-	if len(newSealedSecrets) != len(newSecrets) {
+	if len(newSealedSecrets) != len(values) {
 		fmt.Printf("error creating SealedSecret via kubeseal:\n%s\n",
 			"number of secrets returned do not match number given")
 		os.Exit(1)
 	}
-	if sealedSecret.Spec.EncryptedData == nil {
-		sealedSecret.Spec.EncryptedData = map[string]string{}
-	}
-	for k, v := range newSealedSecrets {
-		sealedSecret.Spec.EncryptedData[k] = v
-	}
+	return newSealedSecrets
 }
@@ -53,7 +53,7 @@ func TestEnvFromFilename(t *testing.T) {
 		},
 	}
 	for _, test := range tests {
-		name, env, err := nameAndEnvFromFilename(test.filename)
+		name, env, err := ProjectConfigDefault().Filenames.NameAndEnvironment(test.filename)
 		if err != nil && !test.expectError {
 			t.Errorf("Unexpected error '%s' from filename '%s'", err, test.filename)
 		}
@@ -125,7 +125,7 @@ apiVersion: "bitnami.com/v1alpha1"
 	i := 0
 	for _, test := range tests {
 		i++
-		sealedSecret, err := sealedSecretFromTemplate(filename, environment, test.data)
+		sealedSecret, err := sealedSecretFromTemplate(filename, environment, test.data, ProjectConfigDefault())
 		if err != nil && !test.expectError {
 			t.Errorf("(Test %d)Unexpected error: %s", i, err)
 		}
@@ -134,3 +134,48 @@ apiVersion: "bitnami.com/v1alpha1"
 		}
 	}
 }
+
+func TestTargetOptionsResolve(t *testing.T) {
+	project := ProjectConfigDefault()
+	tests := []struct {
+		options     targetOptions
+		filename    string
+		expectName  string
+		expectEnv   string
+		expectError bool
+	}{
+		{filename: "secret-example.testing.yaml", expectName: "example-secret", expectEnv: "testing"},
+		{options: targetOptions{name: "other", environment: "staging"}, filename: "secret-example.testing.yaml", expectName: "other", expectEnv: "staging"},
+		{options: targetOptions{name: "other", environment: "staging"}, filename: "other.yaml", expectName: "other", expectEnv: "staging"},
+		{options: targetOptions{name: "other"}, filename: "other.yaml", expectError: true},
+		{options: targetOptions{name: "Invalid_Name"}, filename: "secret-example.testing.yaml", expectError: true},
+		{options: targetOptions{environment: "Prod"}, filename: "secret-example.testing.yaml", expectError: true},
+		{options: targetOptions{namespace: "my-namespace"}, filename: "secret-example.testing.yaml", expectName: "example-secret", expectEnv: "testing"},
+		{options: targetOptions{namespace: "my.namespace"}, filename: "secret-example.testing.yaml", expectError: true},
+	}
+	for _, test := range tests {
+		name, env, err := test.options.resolve(test.filename, project)
+		if (err != nil) != test.expectError {
+			t.Errorf("Expected error %t for %+v and '%s', got: %v", test.expectError, test.options, test.filename, err)
+			continue
+		}
+		if err == nil && (name != test.expectName || env != test.expectEnv) {
+			t.Errorf("Expected '%s' and '%s' for %+v and '%s', got '%s' and '%s'",
+				test.expectName, test.expectEnv, test.options, test.filename, name, env)
+		}
+	}
+}
+
+func TestTargetOptionsCheck(t *testing.T) {
+	sealedSecret := SealedSecret{}
+	sealedSecret.Init("example-secret", "example")
+	if err := (targetOptions{}).check("f.yaml", sealedSecret, "example-secret"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := (targetOptions{}).check("f.yaml", sealedSecret, "other-secret"); err == nil {
+		t.Errorf("Expected error for a different name")
+	}
+	if err := (targetOptions{namespace: "other"}).check("f.yaml", sealedSecret, "example-secret"); err == nil {
+		t.Errorf("Expected error for a different namespace")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+type moveOptions struct {
+	targetOptions
+	toNamespace string
+	noReseal    bool
+	reformat    bool
+}
+
+func (o *moveOptions) flags() []cli.Flag {
+	return append(o.targetOptions.flags("expected `namespace` of the SealedSecret"),
+		&cli.StringFlag{Name: "to-namespace", Destination: &o.toNamespace, Usage: "`namespace` to move the SealedSecret to"},
+		&cli.BoolFlag{Name: "no-reseal", Destination: &o.noReseal, Usage: "fail instead of prompting when the values must be sealed again"},
+		&cli.BoolFlag{Name: "reformat", Destination: &o.reformat, Usage: "re-indent the SealedSecret per the project output settings"},
+	)
+}
+
+// moveInvalidatesCiphertext returns whether ciphertext sealed with scope can
+// no longer be decrypted once the name or namespace changes, see
+// https://github.com/bitnami-labs/sealed-secrets#scopes
+func moveInvalidatesCiphertext(scope string, nameChanged bool, namespaceChanged bool) bool {
+	switch scope {
+	case SealedSecretScope_ClusterWide:
+		return false
+	case SealedSecretScope_NamespaceWide:
+		return namespaceChanged
+	default:
+		return nameChanged || namespaceChanged
+	}
+}
+
+// Move sets the name and namespace of the selected document, both of the
+// SealedSecret and of the Secret it unseals to.
+func (t *SealedSecretTemplate) Move(name string, namespace string) error {
+	err := t.Document.EnsureMapping("spec", "template", "metadata")
+	for _, path := range [][]string{{"metadata"}, {"spec", "template", "metadata"}} {
+		if err == nil {
+			err = t.Document.Set(path, "name", name)
+		}
+		if err == nil {
+			err = t.Document.Set(path, "namespace", namespace)
+		}
+	}
+	return err
+}
+
+// moveTarget returns the path of the file for a SealedSecret renamed to
+// name, which must be a name the filename convention can give, so that the
+// file and the SealedSecret agree.
+func moveTarget(filename string, name string, environment string, project ProjectConfig) (string, error) {
+	suffix := project.Filenames.NameSuffix
+	if !strings.HasSuffix(name, suffix) {
+		return "", fmt.Errorf("new name '%s' must end in '%s' to match the filename, e.g. '%s%s'", name, suffix, name, suffix)
+	}
+	target, err := project.Filenames.Rename(filename, name, environment)
+	if err != nil {
+		return "", err
+	}
+	if targetName, _, err := project.Filenames.NameAndEnvironment(target); err != nil || targetName != name {
+		return "", fmt.Errorf("new name '%s' can't be given by the filename convention, %s would be named '%s'",
+			name, target, targetName)
+	}
+	return target, nil
+}
+
+// documentCount returns the number of SealedSecrets in the file, across the
+// blocks for every environment.
+func (t *SealedSecretTemplate) documentCount() (count int) {
+	for _, block := range t.Blocks {
+		count += len(block.Documents)
+	}
+	return
+}
+
+func move(filename string, newName string, options moveOptions) {
+	file, t, sealedSecret, project, condition := openSealedSecret(filename, options.targetOptions, "move")
+	defer file.Close()
+	if t.documentCount() != 1 {
+		fmt.Printf("Error: %s contains more than one SealedSecret, move only supports files containing a single one\n", filename)
+		os.Exit(1)
+	}
+	name, namespace := sealedSecret.Metadata.Name, sealedSecret.Metadata.Namespace
+	if newName == "" {
+		newName = name
+	}
+	if options.toNamespace != "" {
+		namespace = options.toNamespace
+	}
+	err := validateSecretName(newName)
+	if err == nil && namespace != "" {
+		err = validateNamespace(namespace)
+	}
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	nameChanged := newName != sealedSecret.Metadata.Name
+	namespaceChanged := namespace != sealedSecret.Metadata.Namespace
+	if !nameChanged && !namespaceChanged {
+		fmt.Printf("Error: %s is already named '%s' in namespace '%s'\n", filename, name, namespace)
+		os.Exit(1)
+	}
+	newFilename := filename
+	if nameChanged {
+		newFilename, err = moveTarget(filename, newName, t.Environment, project)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		if _, err := os.Stat(newFilename); err == nil {
+			fmt.Printf("Error: cannot move to %s as file already exists\n", newFilename)
+			os.Exit(1)
+		}
+	}
+
+	var encryptedData map[string]string
+	var certFingerprint string
+	if moveInvalidatesCiphertext(sealedSecret.Scope(), nameChanged, namespaceChanged) {
+		if options.noReseal {
+			fmt.Printf("Error: %s is sealed with %s scope, so its values must be sealed again to be renamed or moved\n",
+				filename, sealedSecret.Scope())
+			os.Exit(1)
+		}
+		fmt.Printf("%s is sealed with %s scope, so its existing values can't be decrypted once moved; enter every value again.\n",
+			filename, sealedSecret.Scope())
+		sealedSecret.Metadata.Name, sealedSecret.Metadata.Namespace = newName, namespace
+		sealedSecret.Spec.Template.Metadata.Name, sealedSecret.Spec.Template.Metadata.Namespace = newName, namespace
+		encryptedData, certFingerprint = sealKeys(&sealedSecret, project, condition, t.Document.Keys("spec", "encryptedData"))
+	}
+
+	t.Filename = newFilename
+	err = t.Move(newName, namespace)
+	if err == nil {
+		err = t.SetEncryptedData(encryptedData)
+	}
+	if err == nil && project.RecordRotation && certFingerprint != "" {
+		err = t.RecordRotation(time.Now(), certFingerprint)
+	}
+	if err == nil {
+		err = t.ApplyOutput(options.reformat)
+	}
+	if err == nil && newFilename == filename {
+		err = t.Write(file)
+	} else if err == nil {
+		var info os.FileInfo
+		if info, err = file.Stat(); err == nil {
+			err = os.WriteFile(newFilename, []byte(t.String()), info.Mode())
+		}
+		if err == nil {
+			err = os.Remove(filename)
+		}
+	}
+	if err != nil {
+		fmt.Printf("error writing SealedSecret file %s: %s\n", newFilename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Moved SealedSecret file '%s' to '%s' with content:\n%s", filename, newFilename, t.String())
+}
+
+func moveCommand() *cli.Command {
+	options := moveOptions{}
+	return &cli.Command{
+		Name:      "move",
+		Usage:     "rename a SealedSecret and its file, or move it to another namespace",
+		ArgsUsage: "(secret-example.environment.yaml) [new-name]",
+		Flags:     options.flags(),
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 1 || c.NArg() > 2 || c.Args().First() == "" {
+				return usageError(c)
+			}
+			if c.NArg() == 1 && options.toNamespace == "" {
+				return usageError(c)
+			}
+			move(c.Args().First(), c.Args().Get(1), options)
+			return nil
+		},
+		BashComplete: completeCommand(map[string]func(c *cli.Context){
+			"env": completeEnvironments,
+		}, func(c *cli.Context, args []string) {
+			if len(args) == 0 {
+				completeSecretFiles(c)
+			}
+		}),
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMoveInvalidatesCiphertext(t *testing.T) {
+	tests := []struct {
+		scope            string
+		nameChanged      bool
+		namespaceChanged bool
+		expect           bool
+	}{
+		{SealedSecretScope_Strict, true, false, true},
+		{SealedSecretScope_Strict, false, true, true},
+		{SealedSecretScope_NamespaceWide, true, false, false},
+		{SealedSecretScope_NamespaceWide, false, true, true},
+		{SealedSecretScope_ClusterWide, true, true, false},
+	}
+	for _, test := range tests {
+		got := moveInvalidatesCiphertext(test.scope, test.nameChanged, test.namespaceChanged)
+		if got != test.expect {
+			t.Errorf("Expected %v for %s scope (name changed: %v, namespace changed: %v), got %v",
+				test.expect, test.scope, test.nameChanged, test.namespaceChanged, got)
+		}
+	}
+}
+
+func TestSealedSecretTemplateMove(t *testing.T) {
+	template := "" +
+		"{{- if eq .Values.environment \"testing\" }}\n" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"  name: example-secret\n" +
+		"  namespace: example\n" +
+		"  annotations:\n" +
+		"    sealedsecrets.bitnami.com/cluster-wide: \"true\"\n" +
+		"spec:\n" +
+		"  encryptedData:\n" +
+		"    PASSWORD: AgBy3i4OJSWK\n" +
+		"  template:\n" +
+		"    metadata:\n" +
+		"      name: example-secret\n" +
+		"      namespace: example\n" +
+		"{{- end }}\n"
+	tmpl, err := parseSealedSecretTemplate("templates/secret-example.testing.yaml", "testing", template, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tmpl.documentCount() != 1 {
+		t.Errorf("Expected 1 document, got %d", tmpl.documentCount())
+	}
+	err = tmpl.Move("renamed-secret", "other")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := strings.NewReplacer("name: example-secret", "name: renamed-secret", "namespace: example", "namespace: other").Replace(template)
+	if tmpl.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, tmpl.String())
+	}
+}
+
+func TestMoveTarget(t *testing.T) {
+	tests := []struct {
+		filename string
+		name     string
+		expect   string
+		err      string
+	}{
+		{filename: "templates/secret-db.testing.yaml", name: "renamed-secret", expect: "templates/secret-renamed.testing.yaml"},
+		{filename: "templates/secret-db.testing.yaml", name: "renamed", err: "new name 'renamed' must end in '-secret' to match the filename, e.g. 'renamed-secret'"},
+		{filename: "templates/db.testing.yaml", name: "renamed-secret", err: "db.testing.yaml"},
+	}
+	for _, test := range tests {
+		target, err := moveTarget(test.filename, test.name, "testing", ProjectConfigDefault())
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Expected error containing '%s' for %s, got: %v", test.err, test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %s", test.name, err)
+			continue
+		}
+		if target != test.expect {
+			t.Errorf("Expected %s, got %s", test.expect, target)
+		}
+		if name, _, _ := ProjectConfigDefault().Filenames.NameAndEnvironment(target); name != test.name {
+			t.Errorf("Expected %s to be named %s by the filename convention, got %s", target, test.name, name)
+		}
+	}
+}
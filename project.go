@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigFilename is the optional per-chart config file. It is looked up
+// in the directory of the secret file being worked on, then each parent
+// directory in turn.
+const ProjectConfigFilename = ".kubesealplus.yaml"
+
+type ProjectConfig struct {
+	Helm              ProjectHelmConfig      `yaml:"helm"`
+	EnvironmentGroups map[string][]string    `yaml:"environmentGroups"`
+	Output            ProjectOutputConfig    `yaml:"output"`
+	Filenames         ProjectFilenamesConfig `yaml:"filenames"`
+	// RecordRotation sets an annotation with the time a SealedSecret was
+	// created or last rotated, as shown by the list command.
+	RecordRotation bool `yaml:"recordRotation"`
+	// DisableFileDetection stops values typed at the prompt being read from a
+	// file just because they are the path of one; file: or @ is needed.
+	DisableFileDetection bool `yaml:"disableFileDetection"`
+}
+
+type ProjectHelmConfig struct {
+	ValuesPath string `yaml:"valuesPath"`
+	Condition  string `yaml:"condition"`
+}
+
+// ProjectOutputConfig controls how SealedSecret YAML is written. The
+// defaults match yaml.v3, which is what earlier versions always wrote.
+type ProjectOutputConfig struct {
+	// Indent is the number of spaces per indentation level.
+	Indent int `yaml:"indent"`
+	// SortKeys keeps spec.encryptedData and spec.template.data sorted by key.
+	SortKeys bool `yaml:"sortKeys"`
+	// OmitNull leaves out fields with a null value, such as `data: null`.
+	OmitNull bool `yaml:"omitNull"`
+	// TrailingNewline ensures files end with a single newline (true) or no
+	// newline (false). When unset, rotate leaves the end of a file as is.
+	TrailingNewline *bool `yaml:"trailingNewline"`
+}
+
+func ProjectConfigDefault() ProjectConfig {
+	return ProjectConfig{
+		Helm: ProjectHelmConfig{
+			ValuesPath: HelmValuesPath_Default,
+			Condition:  HelmConditionShape_Or,
+		},
+		Output: ProjectOutputConfig{
+			Indent: 4,
+		},
+		Filenames: ProjectFilenamesConfig{
+			Pattern:    FilenamePattern_Default,
+			NameSuffix: FilenameNameSuffix_Default,
+		},
+	}
+}
+
+// ProjectConfigFind returns the path of the nearest project config file for
+// the given secret file, or an empty string if there is none.
+func ProjectConfigFind(secretFilename string) (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(secretFilename))
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, ProjectConfigFilename)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// ProjectConfigLoad loads the nearest project config for the given secret
+// file, falling back to defaults for anything not set.
+func ProjectConfigLoad(secretFilename string) (config ProjectConfig, err error) {
+	config = ProjectConfigDefault()
+	configFilename, err := ProjectConfigFind(secretFilename)
+	if err != nil || configFilename == "" {
+		return
+	}
+	content, err := os.ReadFile(configFilename)
+	if err != nil {
+		err = fmt.Errorf("cannot read project config file '%s': %s", configFilename, err)
+		return
+	}
+	err = yaml.Unmarshal(content, &config)
+	if err != nil {
+		err = fmt.Errorf("cannot parse YAML in project config file '%s': %s", configFilename, err)
+		return
+	}
+	err = config.validate()
+	if err != nil {
+		err = fmt.Errorf("invalid project config file '%s': %s", configFilename, err)
+	}
+	return
+}
+
+func (c ProjectConfig) validate() error {
+	if c.Helm.ValuesPath == "" {
+		return fmt.Errorf("helm.valuesPath cannot be empty")
+	}
+	if !isValidValuesPath(c.Helm.ValuesPath) {
+		return fmt.Errorf("helm.valuesPath must be a Helm values reference such as %s, got: %s",
+			HelmValuesPath_Default, c.Helm.ValuesPath)
+	}
+	switch c.Helm.Condition {
+	case HelmConditionShape_Or, HelmConditionShape_Has:
+	default:
+		return fmt.Errorf("helm.condition must be one of '%s' or '%s', got: %s",
+			HelmConditionShape_Or, HelmConditionShape_Has, c.Helm.Condition)
+	}
+	if c.Output.Indent < 2 || c.Output.Indent > 9 {
+		return fmt.Errorf("output.indent must be between 2 and 9, got: %d", c.Output.Indent)
+	}
+	if err := c.Filenames.validate(); err != nil {
+		return err
+	}
+	for group, environments := range c.EnvironmentGroups {
+		if !isValidEnv(group) {
+			return fmt.Errorf("invalid environment group name: %s", group)
+		}
+		if len(environments) == 0 {
+			return fmt.Errorf("environment group '%s' has no environments", group)
+		}
+		for _, environment := range environments {
+			if !isValidEnv(environment) {
+				return fmt.Errorf("invalid environment '%s' in environment group '%s'", environment, group)
+			}
+		}
+	}
+	return nil
+}
+
+// Environments expands an environment taken from a filename into the
+// environments the file is gated on. Environment groups allow a single file
+// to be shared by several environments.
+func (c ProjectConfig) Environments(environment string) []string {
+	if group, exists := c.EnvironmentGroups[environment]; exists {
+		return group
+	}
+	return []string{environment}
+}
+
+// HelmCondition returns the Helm condition wrapping secret files for the
+// given environment (or environment group).
+func (c ProjectConfig) HelmCondition(environment string) HelmCondition {
+	return HelmCondition{
+		ValuesPath:   c.Helm.ValuesPath,
+		Shape:        c.Helm.Condition,
+		Environments: c.Environments(environment),
+	}
+}
+
+// apply applies the null and key ordering settings to a document.
+func (o ProjectOutputConfig) apply(d *yamlDocument) error {
+	if o.OmitNull {
+		if err := d.DeleteNulls(); err != nil {
+			return err
+		}
+	}
+	if o.SortKeys {
+		for _, path := range [][]string{{"spec", "encryptedData"}, {"spec", "template", "data"}} {
+			if err := d.SortKeys(path...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// finish applies the trailing newline setting to the content of a file.
+func (o ProjectOutputConfig) finish(content string) string {
+	if o.TrailingNewline == nil {
+		return content
+	}
+	content = strings.TrimRight(content, "\n")
+	if *o.TrailingNewline {
+		content += "\n"
+	}
+	return content
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProjectConfigLoad(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, "templates")
+	if err := os.Mkdir(templatesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	secretFile := filepath.Join(templatesDir, "secret-example.nonprod.yaml")
+
+	config, err := ProjectConfigLoad(secretFile)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(config, ProjectConfigDefault()) {
+		t.Errorf("Expected default config when no project config exists, got:\n%+v", config)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, ProjectConfigFilename), []byte(""+
+		"helm:\n"+
+		"  valuesPath: .Values.global.environment\n"+
+		"environmentGroups:\n"+
+		"  nonprod: [staging, qa]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err = ProjectConfigLoad(secretFile)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expect := HelmCondition{
+		ValuesPath:   ".Values.global.environment",
+		Shape:        HelmConditionShape_Or,
+		Environments: []string{"staging", "qa"},
+	}
+	if got := config.HelmCondition("nonprod"); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected condition:\n%+v\nGot:\n%+v", expect, got)
+	}
+	if got := config.Environments("production"); !reflect.DeepEqual(got, []string{"production"}) {
+		t.Errorf("Expected ungrouped environment to expand to itself, got: %v", got)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, ProjectConfigFilename), []byte("helm:\n  condition: and\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ProjectConfigLoad(secretFile); err == nil {
+		t.Errorf("Expected error for unsupported condition shape")
+	}
+}
+
+func TestProjectOutputConfigFinish(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		trailingNewline *bool
+		content         string
+		expect          string
+	}{
+		{trailingNewline: nil, content: "a\n\n", expect: "a\n\n"},
+		{trailingNewline: &yes, content: "a", expect: "a\n"},
+		{trailingNewline: &yes, content: "a\n\n", expect: "a\n"},
+		{trailingNewline: &no, content: "a\n", expect: "a"},
+	}
+	for _, test := range tests {
+		got := ProjectOutputConfig{TrailingNewline: test.trailingNewline}.finish(test.content)
+		if got != test.expect {
+			t.Errorf("Expected %q but got %q from %q", test.expect, got, test.content)
+		}
+	}
+}
@@ -2,17 +2,27 @@ package main
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+
+	"golang.org/x/term"
 )
 
 const ANSI_ESCAPE_CLEAR = "\033[H\033[2J"
 
 type PromptSecrets struct {
 	secrets []PromptSecretInput
+	// noFileDetection only reads values from files when given a file prefix,
+	// instead of any value which is the path of a readable file.
+	noFileDetection bool
 }
 
 type PromptSecretInput struct {
@@ -20,6 +30,8 @@ type PromptSecretInput struct {
 	kind          PromptSecretInput_Kind
 	value         string
 	valueFromFile string
+	// source describes where the value came from, as shown when confirming.
+	source string
 }
 
 type PromptSecretInput_Kind string
@@ -28,6 +40,15 @@ const PromptSecretInput_Kind_File PromptSecretInput_Kind = "file"
 const PromptSecretInput_Kind_String PromptSecretInput_Kind = "string"
 const PromptSecretInput_Kind_None PromptSecretInput_Kind = "none"
 
+// Prefixes of a value entered at the prompt which say how it is read.
+const (
+	PromptValuePrefix_File    = "file:"
+	PromptValuePrefix_At      = "@"
+	PromptValuePrefix_Literal = "literal:"
+	PromptValuePrefix_Base64  = "base64:"
+	PromptValuePrefix_Env     = "env:"
+)
+
 func (s *PromptSecrets) InitKey(key string) {
 	if s.secrets == nil {
 		s.secrets = []PromptSecretInput{}
@@ -37,21 +58,98 @@ func (s *PromptSecrets) InitKey(key string) {
 	})
 }
 
-func (s PromptSecrets) ToValues() map[string]string {
-	values := map[string]string{}
-	for _, s := range s.secrets {
-		if s.kind == PromptSecretInput_Kind_None {
-			continue
+// Set sets the value of a key as given, without reading it as a filename.
+// Unless add is set, the key must already have been initialised.
+func (s *PromptSecrets) Set(key string, value string, source string, add bool) error {
+	secret := PromptSecretInput{key: key, kind: PromptSecretInput_Kind_String, value: value, source: source}
+	for i := range s.secrets {
+		if s.secrets[i].key == key {
+			s.secrets[i] = secret
+			return nil
+		}
+	}
+	if !add {
+		return fmt.Errorf("key '%s' is not in spec.encryptedData", key)
+	}
+	s.secrets = append(s.secrets, secret)
+	return nil
+}
+
+// parseValue reads a value entered at the prompt, which may be prefixed to
+// read it from a file (file: or @), an environment variable (env:) or base64
+// (base64:), or to use it as is (literal:). Without a prefix the value is
+// read from a file if it is the path of a regular file, unless
+// noFileDetection is set.
+func (s PromptSecrets) parseValue(key string, value string) (secret PromptSecretInput, err error) {
+	secret = PromptSecretInput{key: key, kind: PromptSecretInput_Kind_String, value: value, source: "typed"}
+	readFile := func(path string, source string) {
+		content, readErr := os.ReadFile(path)
+		if readErr != nil {
+			err = fmt.Errorf("cannot read file for %s: %s", key, readErr)
+			return
+		}
+		secret.kind, secret.value, secret.valueFromFile, secret.source = PromptSecretInput_Kind_File, path, string(content), source
+	}
+	switch {
+	case value == "":
+		secret.kind, secret.source = PromptSecretInput_Kind_None, ""
+	case strings.HasPrefix(value, PromptValuePrefix_File):
+		readFile(strings.TrimPrefix(value, PromptValuePrefix_File), "file")
+	case strings.HasPrefix(value, PromptValuePrefix_At):
+		readFile(strings.TrimPrefix(value, PromptValuePrefix_At), "file")
+	case strings.HasPrefix(value, PromptValuePrefix_Literal):
+		secret.value, secret.source = strings.TrimPrefix(value, PromptValuePrefix_Literal), "literal"
+	case strings.HasPrefix(value, PromptValuePrefix_Base64):
+		decoded, decodeErr := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, PromptValuePrefix_Base64))
+		if decodeErr != nil {
+			return secret, fmt.Errorf("value for %s is not valid base64: %s", key, decodeErr)
 		}
-		if s.kind == PromptSecretInput_Kind_File {
-			values[s.key] = s.valueFromFile
+		secret.value, secret.source = string(decoded), "base64"
+	case strings.HasPrefix(value, PromptValuePrefix_Env):
+		name := strings.TrimPrefix(value, PromptValuePrefix_Env)
+		envValue, exists := os.LookupEnv(name)
+		if !exists {
+			return secret, fmt.Errorf("environment variable %s for %s is not set", name, key)
+		}
+		secret.value, secret.source = envValue, "environment variable "+name
+	case !s.noFileDetection:
+		if info, statErr := os.Stat(value); statErr == nil && info.Mode().IsRegular() {
+			readFile(value, "file, auto-detected")
+		}
+	}
+	return
+}
+
+// Keys returns the keys with a value, and those left unchanged.
+func (s PromptSecrets) Keys() (set []string, unchanged []string) {
+	for _, secret := range s.secrets {
+		if secret.kind == PromptSecretInput_Kind_None {
+			unchanged = append(unchanged, secret.key)
 		} else {
-			values[s.key] = s.value
+			set = append(set, secret.key)
+		}
+	}
+	return
+}
+
+func (s PromptSecrets) ToValues() map[string]string {
+	values := map[string]string{}
+	for _, secret := range s.secrets {
+		if secret.kind != PromptSecretInput_Kind_None {
+			values[secret.key] = s.valueOf(secret)
 		}
 	}
 	return values
 }
 
+// valueOf returns the value to be sealed for a secret.
+func (s PromptSecrets) valueOf(secret PromptSecretInput) string {
+	if secret.kind == PromptSecretInput_Kind_File {
+		return secret.valueFromFile
+	}
+	return secret.value
+}
+
 func (s *PromptSecrets) Namespace(input io.Reader, output io.Writer) (namespace string, err error) {
 	fmt.Fprintf(
 		output,
@@ -72,6 +170,10 @@ func (s *PromptSecrets) Namespace(input io.Reader, output io.Writer) (namespace
 			fmt.Fprintf(output, "WARNING: Invalid namespace values are ignored, please re-enter a namespace.\n")
 			continue
 		}
+		if err = validateNamespace(line); err != nil {
+			fmt.Fprintf(output, "WARNING: %s\nPlease re-enter a namespace.\n", err)
+			continue
+		}
 		return line, nil
 	}
 }
@@ -84,14 +186,13 @@ func (s *PromptSecrets) Enter(input io.Reader, output io.Writer) (err error) {
 		strings.Repeat(`-`, 80),
 	)
 	reader := bufio.NewReader(input)
-	i := 0
+	hidden := promptIsTerminal(input)
 	for {
 		var line string
-		line, err = reader.ReadString('\n')
+		line, err = promptReadLine(reader, input, output, hidden)
 		if err != nil {
 			return
 		}
-		line = strings.TrimSuffix(line, "\n")
 		if line == "" {
 			break
 		}
@@ -100,22 +201,15 @@ func (s *PromptSecrets) Enter(input io.Reader, output io.Writer) (err error) {
 			fmt.Fprintf(output, "WARNING: Lines not containing key and value separated by '=' are ignored\n")
 			continue
 		}
-		key := strings.TrimSpace(lineSplit[0])
-		value := lineSplit[1]
-		s.secrets = append(s.secrets, PromptSecretInput{
-			key:   key,
-			value: strings.TrimSpace(value),
-		})
-		valueFromFile, readFileErr := os.ReadFile(value)
-		if value == "" {
-			s.secrets[i].kind = PromptSecretInput_Kind_None
-		} else if readFileErr == nil {
-			s.secrets[i].kind = PromptSecretInput_Kind_File
-			s.secrets[i].valueFromFile = string(valueFromFile)
-		} else {
-			s.secrets[i].kind = PromptSecretInput_Kind_String
+		secret, parseErr := s.parseValue(strings.TrimSpace(lineSplit[0]), strings.TrimSpace(lineSplit[1]))
+		if parseErr != nil {
+			fmt.Fprintf(output, "WARNING: %s, line ignored\n", parseErr)
+			continue
 		}
-		i++
+		if hidden {
+			fmt.Fprintf(output, "%s=(%s)\n", secret.key, PromptMask(s.valueOf(secret)))
+		}
+		s.secrets = append(s.secrets, secret)
 	}
 	return
 }
@@ -133,27 +227,102 @@ func (s *PromptSecrets) Update(redo int, input io.Reader, output io.Writer) (err
 			continue
 		}
 		fmt.Fprintf(output, "%s=", secret.key)
-		var value string
-		value, err = reader.ReadString('\n')
+		for {
+			var value string
+			value, err = promptReadLine(reader, input, output, true)
+			if err != nil {
+				return
+			}
+			var parseErr error
+			s.secrets[i], parseErr = s.parseValue(secret.key, strings.TrimSpace(value))
+			if parseErr == nil {
+				break
+			}
+			fmt.Fprintf(output, "WARNING: %s, please re-enter a value.\n%s=", parseErr, secret.key)
+		}
+	}
+	return
+}
+
+// PromptField is a single input of a typed Secret flow, such as a registry
+// password or the path of a TLS cert. Fields with a Key are stored directly
+// under that key in the Secret.
+type PromptField struct {
+	Name        string
+	Description string
+	File        bool
+	Optional    bool
+	Key         string
+	// Hidden fields are read with echo disabled on a terminal.
+	Hidden bool
+}
+
+// Typed prompts for each field of a typed Secret, re-prompting for all fields
+// until they pass the builder's validation.
+func (s *PromptSecrets) Typed(builder SecretTypeBuilder, input io.Reader, output io.Writer) (err error) {
+	fmt.Fprintf(
+		output,
+		"%s%s\n\nPlease enter the fields for a %s Secret then press enter:\n",
+		ANSI_ESCAPE_CLEAR,
+		strings.Repeat(`-`, 80),
+		builder.Type,
+	)
+	reader := bufio.NewReader(input)
+	for {
+		fields := map[string]string{}
+		s.secrets = []PromptSecretInput{}
+		for _, field := range builder.Fields {
+			fmt.Fprintf(output, "# %s\n", field.Description)
+			for {
+				fmt.Fprintf(output, "%s=", field.Name)
+				var line string
+				line, err = promptReadLine(reader, input, output, field.Hidden)
+				if err != nil {
+					return
+				}
+				line = strings.TrimSpace(line)
+				if line == "" && !field.Optional {
+					fmt.Fprintf(output, "WARNING: %s is required, please re-enter a value.\n", field.Name)
+					continue
+				}
+				secret := PromptSecretInput{
+					key:   field.Key,
+					kind:  PromptSecretInput_Kind_String,
+					value: line,
+				}
+				if field.File && line != "" {
+					valueFromFile, readFileErr := os.ReadFile(line)
+					if readFileErr != nil {
+						fmt.Fprintf(output, "WARNING: cannot read file: %s\n", readFileErr)
+						continue
+					}
+					secret.kind = PromptSecretInput_Kind_File
+					secret.valueFromFile = string(valueFromFile)
+					fields[field.Name] = secret.valueFromFile
+				} else {
+					fields[field.Name] = line
+				}
+				if field.Key != "" && line != "" {
+					s.secrets = append(s.secrets, secret)
+				}
+				break
+			}
+		}
+		var computed map[string]string
+		computed, err = builder.Build(fields)
 		if err != nil {
-			return
+			fmt.Fprintf(output, "ERROR: %s\nPlease re-enter the fields.\n", err)
+			continue
 		}
-		value = strings.TrimSuffix(value, "\n")
-		s.secrets[i] = PromptSecretInput{
-			key:   secret.key,
-			value: strings.TrimSpace(value),
-		}
-		valueFromFile, readFileErr := os.ReadFile(value)
-		if value == "" {
-			s.secrets[i].kind = PromptSecretInput_Kind_None
-		} else if readFileErr == nil {
-			s.secrets[i].kind = PromptSecretInput_Kind_File
-			s.secrets[i].valueFromFile = string(valueFromFile)
-		} else {
-			s.secrets[i].kind = PromptSecretInput_Kind_String
+		for _, key := range sortedKeys(computed) {
+			s.secrets = append(s.secrets, PromptSecretInput{
+				key:   key,
+				kind:  PromptSecretInput_Kind_String,
+				value: computed[key],
+			})
 		}
+		return nil
 	}
-	return
 }
 
 func (s *PromptSecrets) Confirm(input io.Reader, output io.Writer) (redo int, err error) {
@@ -165,15 +334,19 @@ func (s *PromptSecrets) Confirm(input io.Reader, output io.Writer) (redo int, er
 		strings.Repeat(`-`, 80),
 	)
 	for i, s := range s.secrets {
+		source := s.source
+		if source == "" {
+			source = "typed"
+		}
 		switch s.kind {
 		case PromptSecretInput_Kind_File:
-			fmt.Fprintf(output, "%d. %s will contain the contents of file %s\n", i+1, s.key, s.value)
+			fmt.Fprintf(output, "%d. %s will contain the contents of file %s (%s; %s)\n", i+1, s.key, s.value, PromptMask(s.valueFromFile), source)
 		case PromptSecretInput_Kind_None:
 			fmt.Fprintf(output, "%d. %s will remain unchanged\n", i+1, s.key)
 		case PromptSecretInput_Kind_String:
 			fallthrough
 		default:
-			fmt.Fprintf(output, "%d. %s=%s\n", i+1, s.key, s.value)
+			fmt.Fprintf(output, "%d. %s (%s; %s)\n", i+1, s.key, PromptMask(s.value), source)
 		}
 	}
 	for {
@@ -181,7 +354,7 @@ func (s *PromptSecrets) Confirm(input io.Reader, output io.Writer) (redo int, er
 		if numString == "1-1" {
 			numString = "1"
 		}
-		fmt.Fprintf(output, "\nEnter the secret number to change the value, or Y to confirm\n%s or Y: ", numString)
+		fmt.Fprintf(output, "\nEnter the secret number to change the value, r and the number to reveal it, or Y to confirm\n%s, r%s or Y: ", numString, numString)
 		in, err := reader.ReadString('\n')
 		if err != nil {
 			break
@@ -190,6 +363,11 @@ func (s *PromptSecrets) Confirm(input io.Reader, output io.Writer) (redo int, er
 		if in == "Y" {
 			break
 		}
+		if reveal, err := strconv.Atoi(strings.TrimPrefix(in, "r")); strings.HasPrefix(in, "r") && err == nil &&
+			reveal >= 1 && reveal <= len(s.secrets) && s.secrets[reveal-1].kind != PromptSecretInput_Kind_None {
+			fmt.Fprintf(output, "%s=%s\n", s.secrets[reveal-1].key, s.ToValues()[s.secrets[reveal-1].key])
+			continue
+		}
 		redo, err = strconv.Atoi(in)
 		if err == nil && redo >= 1 && redo <= len(s.secrets) {
 			break
@@ -201,6 +379,79 @@ func (s *PromptSecrets) Confirm(input io.Reader, output io.Writer) (redo int, er
 	return
 }
 
+// Choose prompts for one of the given options, returning its index.
+func (s *PromptSecrets) Choose(question string, options []string, input io.Reader, output io.Writer) (choice int, err error) {
+	fmt.Fprintf(
+		output,
+		"%s%s\n\n%s\n",
+		ANSI_ESCAPE_CLEAR,
+		strings.Repeat(`-`, 80),
+		question,
+	)
+	for i, option := range options {
+		fmt.Fprintf(output, "%d. %s\n", i+1, option)
+	}
+	reader := bufio.NewReader(input)
+	for {
+		fmt.Fprintf(output, "\nEnter a number (1-%d): ", len(options))
+		var in string
+		in, err = reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		choice, err = strconv.Atoi(strings.TrimSpace(in))
+		if err == nil && choice >= 1 && choice <= len(options) {
+			return choice - 1, nil
+		}
+		fmt.Fprintf(output, "ERROR: Input invalid, please retry.\n")
+	}
+}
+
+// PromptMask describes a value without showing it, by its length and the
+// start of its SHA-256 hash, so values can be compared but not read.
+func PromptMask(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%d bytes, sha256:%s", len(value), hex.EncodeToString(sum[:])[:8])
+}
+
+// promptIsTerminal returns whether input is a terminal, where values are read
+// with echo disabled.
+func promptIsTerminal(input io.Reader) bool {
+	file, ok := input.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// promptReadLine reads a line without its newline. If hidden and input is a
+// terminal, echo is disabled while reading, and restored if interrupted.
+func promptReadLine(reader *bufio.Reader, input io.Reader, output io.Writer, hidden bool) (string, error) {
+	if !hidden || !promptIsTerminal(input) {
+		line, err := reader.ReadString('\n')
+		return strings.TrimSuffix(line, "\n"), err
+	}
+	fd := int(input.(*os.File).Fd())
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", err
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	defer close(done)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			term.Restore(fd, state)
+			fmt.Fprintln(output)
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	line, err := term.ReadPassword(fd)
+	fmt.Fprintln(output)
+	return string(line), err
+}
+
 func PromptClear(output io.Writer) {
 	fmt.Fprint(output, ANSI_ESCAPE_CLEAR)
 }
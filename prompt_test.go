@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -48,3 +50,145 @@ func TestPromptConfirm(t *testing.T) {
 		t.Errorf("Expected redo is zero, got:\n%d", redo)
 	}
 }
+
+func TestPromptTyped(t *testing.T) {
+	in := bytes.Buffer{}
+	out := bytes.Buffer{}
+	// first attempt fails validation as both fields are empty
+	in.WriteString("\n\nadmin\nhunter2\n")
+	builder, _ := secretTypeBuilderFor("basic-auth")
+	secrets := PromptSecrets{}
+	err := secrets.Typed(builder, &in, &out)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	values := secrets.ToValues()
+	if len(values) != 2 || values["username"] != "admin" || values["password"] != "hunter2" {
+		t.Errorf("Expected username and password, got:\n%v", values)
+	}
+}
+
+func TestPromptMask(t *testing.T) {
+	tests := []struct {
+		value  string
+		expect string
+	}{
+		{"", "0 bytes, sha256:e3b0c442"},
+		{"hunter2", "7 bytes, sha256:f52fbd32"},
+	}
+	for _, test := range tests {
+		if got := PromptMask(test.value); got != test.expect {
+			t.Errorf("Expected %s for %q, got %s", test.expect, test.value, got)
+		}
+	}
+}
+
+func TestPromptConfirmMasked(t *testing.T) {
+	in := bytes.Buffer{}
+	out := bytes.Buffer{}
+	in.WriteString("r1\nr2\nr3\n2\n")
+	secrets := PromptSecrets{}
+	secrets.Set("PASSWORD", "hunter2", ".env:1", true)
+	secrets.InitKey("TOKEN")
+	secrets.secrets[1].kind = PromptSecretInput_Kind_None
+	redo, err := secrets.Confirm(&in, &out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if redo != 2 {
+		t.Errorf("Expected redo is 2, got: %d", redo)
+	}
+	output := out.String()
+	if !strings.Contains(output, "1. PASSWORD (7 bytes, sha256:f52fbd32; .env:1)\n") {
+		t.Errorf("Expected masked value, got:\n%s", output)
+	}
+	if strings.Count(output, "hunter2") != 1 || !strings.Contains(output, "PASSWORD=hunter2\n") {
+		t.Errorf("Expected value only shown once when revealed, got:\n%s", output)
+	}
+	if strings.Count(output, "ERROR: Input invalid") != 2 {
+		t.Errorf("Expected revealing an unchanged key and a key out of range to be invalid, got:\n%s", output)
+	}
+}
+
+func TestPromptParseValue(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password.txt")
+	writeTestFile(t, file, "from file\n")
+	t.Setenv("TEST_KUBESEALPLUS_PASSWORD", "from env")
+	tests := []struct {
+		value           string
+		noFileDetection bool
+		kind            PromptSecretInput_Kind
+		expect          string
+		source          string
+		err             string
+	}{
+		{value: "", kind: PromptSecretInput_Kind_None},
+		{value: "admin", kind: PromptSecretInput_Kind_String, expect: "admin", source: "typed"},
+		{value: file, kind: PromptSecretInput_Kind_File, expect: "from file\n", source: "file, auto-detected"},
+		{value: file, noFileDetection: true, kind: PromptSecretInput_Kind_String, expect: file, source: "typed"},
+		{value: "/", kind: PromptSecretInput_Kind_String, expect: "/", source: "typed"},
+		{value: filepath.Dir(file), kind: PromptSecretInput_Kind_String, expect: filepath.Dir(file), source: "typed"},
+		{value: "@" + file, noFileDetection: true, kind: PromptSecretInput_Kind_File, expect: "from file\n", source: "file"},
+		{value: "file:" + file, kind: PromptSecretInput_Kind_File, expect: "from file\n", source: "file"},
+		{value: "literal:" + file, kind: PromptSecretInput_Kind_String, expect: file, source: "literal"},
+		{value: "literal:@admin", kind: PromptSecretInput_Kind_String, expect: "@admin", source: "literal"},
+		{value: "base64:AAEC/w==", kind: PromptSecretInput_Kind_String, expect: "\x00\x01\x02\xff", source: "base64"},
+		{value: "env:TEST_KUBESEALPLUS_PASSWORD", kind: PromptSecretInput_Kind_String, expect: "from env", source: "environment variable TEST_KUBESEALPLUS_PASSWORD"},
+		{value: "@" + file + ".missing", err: "cannot read file for KEY"},
+		{value: "base64:!!", err: "value for KEY is not valid base64"},
+		{value: "env:TEST_KUBESEALPLUS_UNSET", err: "environment variable TEST_KUBESEALPLUS_UNSET for KEY is not set"},
+	}
+	for _, test := range tests {
+		secrets := PromptSecrets{noFileDetection: test.noFileDetection}
+		secret, err := secrets.parseValue("KEY", test.value)
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("Expected error starting '%s' for %q, got: %v", test.err, test.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", test.value, err)
+		} else if secret.kind != test.kind || secrets.valueOf(secret) != test.expect || secret.source != test.source {
+			t.Errorf("Expected %s %q from %q for %q, got %s %q from %q",
+				test.kind, test.expect, test.source, test.value, secret.kind, secrets.valueOf(secret), secret.source)
+		}
+	}
+}
+
+func TestPromptEnterPrefixes(t *testing.T) {
+	in := bytes.Buffer{}
+	out := bytes.Buffer{}
+	in.WriteString("A=literal:/\nB=base64:!!\nC=/\n\n")
+	secrets := PromptSecrets{noFileDetection: true}
+	if err := secrets.Enter(&in, &out); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	values := secrets.ToValues()
+	if len(values) != 2 || values["A"] != "/" || values["C"] != "/" {
+		t.Errorf("Expected A and C set to /, got:\n%v", values)
+	}
+	in.WriteString("A=/\n\n")
+	detected := PromptSecrets{}
+	if err := detected.Enter(&in, &out); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if values := detected.ToValues(); len(values) != 1 || values["A"] != "/" {
+		t.Errorf("Expected a directory to be used as a string with file detection on, got:\n%v", values)
+	}
+	if !strings.Contains(out.String(), "WARNING: value for B is not valid base64") {
+		t.Errorf("Expected warning for invalid base64, got:\n%s", out.String())
+	}
+
+	in.WriteString("env:TEST_KUBESEALPLUS_UNSET\nliteral:x\n")
+	out.Reset()
+	if err := secrets.Update(1, &in, &out); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if values := secrets.ToValues(); values["A"] != "x" {
+		t.Errorf("Expected A re-entered as x, got:\n%v", values)
+	}
+	if !strings.Contains(out.String(), "please re-enter a value") {
+		t.Errorf("Expected to be asked to re-enter, got:\n%s", out.String())
+	}
+}
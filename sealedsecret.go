@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,19 +16,52 @@ import (
 )
 
 type SealedSecret struct {
-	Environment string             `json:"-" yaml:"-"`
-	ApiVersion  string             `json:"apiVersion" yaml:"apiVersion"`
-	Kind        string             `json:"kind" yaml:"kind"`
-	Metadata    map[string]*string `json:"metadata" yaml:"metadata"`
-	Spec        struct {
-		EncryptedData map[string]string `json:"encryptedData,omitempty" yaml:"encryptedData,omitempty"`
-		Template      struct {
-			Data     *map[string]*string `json:"data" yaml:"data"`
-			Metadata map[string]*string  `json:"metadata" yaml:"metadata"`
-		} `json:"template" yaml:"template"`
-	} `json:"spec" yaml:"spec"`
+	Environment string           `json:"-" yaml:"-"`
+	ApiVersion  string           `json:"apiVersion" yaml:"apiVersion"`
+	Kind        string           `json:"kind" yaml:"kind"`
+	Metadata    ObjectMeta       `json:"metadata" yaml:"metadata"`
+	Spec        SealedSecretSpec `json:"spec" yaml:"spec"`
 }
 
+type SealedSecretSpec struct {
+	EncryptedData map[string]string `json:"encryptedData,omitempty" yaml:"encryptedData,omitempty"`
+	Template      SecretTemplate    `json:"template" yaml:"template"`
+}
+
+// SecretTemplate describes the Secret the controller creates when unsealing.
+type SecretTemplate struct {
+	Data      *map[string]string `json:"data" yaml:"data"`
+	Metadata  ObjectMeta         `json:"metadata" yaml:"metadata"`
+	Type      string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Immutable *bool              `json:"immutable,omitempty" yaml:"immutable,omitempty"`
+}
+
+type ObjectMeta struct {
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Annotations on a SealedSecret which control how kubeseal scopes the
+// ciphertext, and so must be passed back to kubeseal when re-sealing.
+const SealedSecretAnnotation_NamespaceWide = "sealedsecrets.bitnami.com/namespace-wide"
+const SealedSecretAnnotation_ClusterWide = "sealedsecrets.bitnami.com/cluster-wide"
+
+// Annotations recording when a SealedSecret was last sealed and the
+// fingerprint of the cert it was sealed with, if enabled by recordRotation in
+// the project config.
+const SealedSecretAnnotation_LastRotated = "kubesealplus/last-rotated"
+const SealedSecretAnnotation_CertFingerprint = "kubesealplus/cert-fingerprint"
+
+// Scopes a SealedSecret can be sealed with, see
+// https://github.com/bitnami-labs/sealed-secrets#scopes
+const (
+	SealedSecretScope_Strict        = "strict"
+	SealedSecretScope_NamespaceWide = "namespace-wide"
+	SealedSecretScope_ClusterWide   = "cluster-wide"
+)
+
 func createSealedSecrets(secretYAML string, certFilename string) (sealedSecrets map[string]string, err error) {
 	ctx, timeout := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer timeout()
@@ -56,71 +90,132 @@ func createSealedSecrets(secretYAML string, certFilename string) (sealedSecrets
 	return sealedSecret.Spec.EncryptedData, nil
 }
 
-const firstLineTemplate = "{{- if eq .Values.environment \"%s\" }}"
 const lastLineTemplate = `{{- end }}`
 
 func (s *SealedSecret) Init(name string, namespace string) {
 	s.ApiVersion = "bitnami.com/v1alpha1"
 	s.Kind = "SealedSecret"
-	s.Metadata = map[string]*string{
-		"name":      &name,
-		"namespace": &namespace,
+	s.Metadata = ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+	}
+	s.Spec.Template.Metadata = ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
 	}
-	s.Spec.Template.Metadata = s.Metadata
 }
 
-func sealedSecretFromTemplate(filename string, environment string, template string) (sealedSecret SealedSecret, err error) {
-	expectFirstLine := fmt.Sprintf(firstLineTemplate, environment)
-	const expectLastLine = lastLineTemplate
-	template = strings.TrimSpace(template)
-	lines := strings.Split(template, "\n")
-	if len(lines) < 3 {
-		err = fmt.Errorf("template file %s needs to contain at least 3 lines", filename)
-		return
+// RecordRotation sets the annotations recording when and with which cert the
+// SealedSecret was sealed.
+func (s *SealedSecret) RecordRotation(now time.Time, certFingerprint string) {
+	if s.Metadata.Annotations == nil {
+		s.Metadata.Annotations = map[string]string{}
+	}
+	for k, v := range rotationAnnotations(now, certFingerprint) {
+		s.Metadata.Annotations[k] = v
 	}
+}
 
-	firstLine := strings.TrimSpace(lines[0])
-	lastLine := strings.TrimSpace(lines[len(lines)-1])
-	manifestLines := bytes.Buffer{}
-	i := 0
-	for _, line := range lines {
-		i++
-		if i == 1 || i == len(lines) {
-			continue
-		}
-		manifestLines.WriteString(line)
-		manifestLines.WriteString("\n")
+func rotationAnnotations(now time.Time, certFingerprint string) map[string]string {
+	annotations := map[string]string{
+		SealedSecretAnnotation_LastRotated: now.UTC().Format(time.RFC3339),
+	}
+	if certFingerprint != "" {
+		annotations[SealedSecretAnnotation_CertFingerprint] = certFingerprint
 	}
+	return annotations
+}
 
-	if firstLine != expectFirstLine {
-		err = fmt.Errorf("first line of template (%s) not in expected format.\nExpected:\n%s\nGot:\n%s", filename, expectFirstLine, firstLine)
-		return
+// Scope returns the scope the SealedSecret was sealed with.
+func (s *SealedSecret) Scope() string {
+	annotations := s.SealingMetadata().Annotations
+	switch {
+	case annotations[SealedSecretAnnotation_ClusterWide] == "true":
+		return SealedSecretScope_ClusterWide
+	case annotations[SealedSecretAnnotation_NamespaceWide] == "true":
+		return SealedSecretScope_NamespaceWide
 	}
-	if lastLine != expectLastLine {
-		err = fmt.Errorf("last line of template (%s) not in expected format.\nExpected:\n%s\nGot:\n%s", filename, expectLastLine, lastLine)
-		return
+	return SealedSecretScope_Strict
+}
+
+// SealingMetadata returns the metadata of the Secret given to kubeseal: the
+// template metadata, plus any scope annotations from the SealedSecret itself.
+func (s *SealedSecret) SealingMetadata() ObjectMeta {
+	metadata := ObjectMeta{
+		Name:        s.Spec.Template.Metadata.Name,
+		Namespace:   s.Spec.Template.Metadata.Namespace,
+		Labels:      s.Spec.Template.Metadata.Labels,
+		Annotations: map[string]string{},
 	}
-	err = yaml.Unmarshal(manifestLines.Bytes(), &sealedSecret)
+	if metadata.Name == "" {
+		metadata.Name = s.Metadata.Name
+	}
+	if metadata.Namespace == "" {
+		metadata.Namespace = s.Metadata.Namespace
+	}
+	for k, v := range s.Spec.Template.Metadata.Annotations {
+		metadata.Annotations[k] = v
+	}
+	for _, k := range []string{SealedSecretAnnotation_NamespaceWide, SealedSecretAnnotation_ClusterWide} {
+		if v, exists := s.Metadata.Annotations[k]; exists {
+			metadata.Annotations[k] = v
+		}
+	}
+	if len(metadata.Annotations) == 0 {
+		metadata.Annotations = nil
+	}
+	return metadata
+}
+
+func sealedSecretFromTemplate(filename string, environment string, template string, project ProjectConfig) (sealedSecret SealedSecret, err error) {
+	t, err := parseSealedSecretTemplate(filename, environment, template, project)
 	if err != nil {
-		err = fmt.Errorf("template (with first and last line removed) does not contain valid YAML (%s):\n%s", err, manifestLines.String())
 		return
 	}
+	return t.SealedSecret()
+}
 
-	sealedSecret.Environment = environment
-	return
+func (ss *SealedSecret) ToYAML(output ProjectOutputConfig) ([]byte, error) {
+	escaped := *ss
+	escaped.Spec.Template.Data = mapTemplateData(ss.Spec.Template.Data, escapeTemplateData)
+	manifest, err := yaml.Marshal(escaped)
+	if err != nil {
+		return nil, err
+	}
+	document, err := parseYAMLDocument(string(manifest))
+	if err != nil {
+		return nil, err
+	}
+	err = output.apply(document)
+	if err != nil {
+		return nil, err
+	}
+	err = document.Reformat(output.Indent)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(document.String()), nil
 }
 
-func (ss *SealedSecret) ToTemplate(f *os.File, environment string) (out bytes.Buffer, err error) {
-	template, err := yaml.Marshal(ss)
+func (ss *SealedSecret) ToTemplate(f *os.File, condition HelmCondition, output ProjectOutputConfig) (out bytes.Buffer, err error) {
+	template, err := ss.ToYAML(output)
 	if err != nil {
 		return
 	}
+	content := output.finish(condition.FirstLine() + "\n" + string(template) + lastLineTemplate + "\n")
 	f.Truncate(0)
 	f.Seek(0, io.SeekStart)
 	for _, writer := range []io.StringWriter{f, &out} {
-		writer.WriteString(fmt.Sprintf(firstLineTemplate+"\n", environment))
-		writer.WriteString(string(template))
-		writer.WriteString(fmt.Sprintf(lastLineTemplate + "\n"))
+		writer.WriteString(content)
 	}
 	return
 }
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSealingMetadata(t *testing.T) {
+	sealedSecret := SealedSecret{}
+	sealedSecret.Init("example-secret", "example")
+	sealedSecret.Metadata.Annotations = map[string]string{
+		SealedSecretAnnotation_NamespaceWide: "true",
+		"example.com/owner":                  "team",
+	}
+	sealedSecret.Spec.Template.Metadata.Labels = map[string]string{"app": "example"}
+
+	expect := ObjectMeta{
+		Name:        "example-secret",
+		Namespace:   "example",
+		Labels:      map[string]string{"app": "example"},
+		Annotations: map[string]string{SealedSecretAnnotation_NamespaceWide: "true"},
+	}
+	got := sealedSecret.SealingMetadata()
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected:\n%+v\nGot:\n%+v", expect, got)
+	}
+}
+
+func TestSealedSecretFromTemplateModel(t *testing.T) {
+	template := `{{- if eq .Values.environment "testing" }}
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: example-secret
+  namespace: example
+  labels:
+    app: example
+  annotations:
+    sealedsecrets.bitnami.com/managed: "true"
+spec:
+  encryptedData:
+    tls.crt: AgBy3i4OJSWK
+    tls.key: AgAKAoiQm7xF
+  template:
+    type: kubernetes.io/tls
+    immutable: true
+    metadata:
+      name: example-secret
+      namespace: example
+      labels:
+        app: example
+{{- end }}`
+	sealedSecret, err := sealedSecretFromTemplate("templates/secret-example.testing.yaml", "testing", template, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if sealedSecret.Metadata.Labels["app"] != "example" ||
+		sealedSecret.Metadata.Annotations["sealedsecrets.bitnami.com/managed"] != "true" {
+		t.Errorf("Expected labels and annotations to be parsed, got:\n%+v", sealedSecret.Metadata)
+	}
+	if sealedSecret.Spec.Template.Type != "kubernetes.io/tls" {
+		t.Errorf("Expected template type kubernetes.io/tls, got: %s", sealedSecret.Spec.Template.Type)
+	}
+	if sealedSecret.Spec.Template.Immutable == nil || !*sealedSecret.Spec.Template.Immutable {
+		t.Errorf("Expected template to be immutable")
+	}
+	if sealedSecret.Spec.Template.Metadata.Labels["app"] != "example" {
+		t.Errorf("Expected template labels to be parsed, got:\n%+v", sealedSecret.Spec.Template.Metadata)
+	}
+}
+
+func TestSealedSecretToYAML(t *testing.T) {
+	sealedSecret := SealedSecret{}
+	sealedSecret.Init("example-secret", "example")
+	sealedSecret.Spec.EncryptedData = map[string]string{"B": "AgB==", "A": "AgA=="}
+
+	got, err := sealedSecret.ToYAML(ProjectConfigDefault().Output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := "" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"    name: example-secret\n" +
+		"    namespace: example\n" +
+		"spec:\n" +
+		"    encryptedData:\n" +
+		"        A: AgA==\n" +
+		"        B: AgB==\n" +
+		"    template:\n" +
+		"        data: null\n" +
+		"        metadata:\n" +
+		"            name: example-secret\n" +
+		"            namespace: example\n"
+	if string(got) != expect {
+		t.Errorf("Expected default output:\n%s\nGot:\n%s", expect, got)
+	}
+
+	got, err = sealedSecret.ToYAML(ProjectOutputConfig{Indent: 2, OmitNull: true, SortKeys: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect = "" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"  name: example-secret\n" +
+		"  namespace: example\n" +
+		"spec:\n" +
+		"  encryptedData:\n" +
+		"    A: AgA==\n" +
+		"    B: AgB==\n" +
+		"  template:\n" +
+		"    metadata:\n" +
+		"      name: example-secret\n" +
+		"      namespace: example\n"
+	if string(got) != expect {
+		t.Errorf("Expected configured output:\n%s\nGot:\n%s", expect, got)
+	}
+}
+
+func TestSealedSecretScope(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		expect      string
+	}{
+		{nil, SealedSecretScope_Strict},
+		{map[string]string{SealedSecretAnnotation_NamespaceWide: "true"}, SealedSecretScope_NamespaceWide},
+		{map[string]string{SealedSecretAnnotation_ClusterWide: "true"}, SealedSecretScope_ClusterWide},
+		{map[string]string{SealedSecretAnnotation_ClusterWide: "false"}, SealedSecretScope_Strict},
+	}
+	for _, test := range tests {
+		sealedSecret := SealedSecret{}
+		sealedSecret.Init("example-secret", "example")
+		sealedSecret.Metadata.Annotations = test.annotations
+		if got := sealedSecret.Scope(); got != test.expect {
+			t.Errorf("Expected scope '%s' for %v, got '%s'", test.expect, test.annotations, got)
+		}
+	}
+}
@@ -9,21 +9,27 @@ import (
 )
 
 type secretManifest struct {
-	ApiVersion string             `yaml:"apiVersion"`
-	Kind       string             `yaml:"kind"`
-	Type       string             `yaml:"type"`
-	Data       map[string]string  `yaml:"data"`
-	Metadata   map[string]*string `yaml:"metadata"`
+	ApiVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
 }
 
+const SecretType_Opaque = "Opaque"
+
 func createSecretYAML(
-	metadata map[string]*string,
+	metadata ObjectMeta,
+	secretType string,
 	secrets map[string]string,
 ) (manifestYAML string, err error) {
+	if secretType == "" {
+		secretType = SecretType_Opaque
+	}
 	manifest := secretManifest{
 		ApiVersion: "v1",
 		Kind:       "Secret",
-		Type:       "Opaque",
+		Type:       secretType,
 		Data:       map[string]string{},
 		Metadata:   metadata,
 	}
@@ -41,17 +47,40 @@ func createSecretYAML(
 func validateSecretName(name string) error {
 	// https://kubernetes.io/docs/concepts/configuration/secret/#restriction-names-data
 	// https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#dns-subdomain-names
-	if len(name) > 63 {
-		return fmt.Errorf("secret name cannot exceed 63 characters per RFC 1123")
+	return validateRFC1123Label("secret name", name)
+}
+
+var isValidSecretKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`).MatchString
+
+// validateSecretKey checks a key of a Secret's data is valid, per
+// https://kubernetes.io/docs/concepts/configuration/secret/#restriction-names-data
+func validateSecretKey(key string) error {
+	if len(key) > 253 {
+		return fmt.Errorf("key '%s' cannot exceed 253 characters", key)
+	}
+	if !isValidSecretKey(key) || key == "." || key == ".." {
+		return fmt.Errorf("key '%s' must only contain alphanumeric characters, -, _ or .", key)
+	}
+	return nil
+}
+
+func validateNamespace(namespace string) error {
+	// https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#dns-label-names
+	return validateRFC1123Label("namespace", namespace)
+}
+
+func validateRFC1123Label(what string, value string) error {
+	if len(value) > 63 {
+		return fmt.Errorf("%s cannot exceed 63 characters per RFC 1123", what)
 	}
-	match, err := regexp.MatchString("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$", name)
+	match, err := regexp.MatchString("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$", value)
 	if err != nil {
 		return err
 	}
 	if !match {
 		return fmt.Errorf(
-			"%s.\n- %s\n- %s\n- %s",
-			"secret name must be valid DNS subdomain",
+			"%s '%s' must be a valid RFC 1123 label.\n- %s\n- %s\n- %s",
+			what, value,
 			"must only contain lowercase alphanumeric characters or -",
 			"must start with a lowercase alphanumeric character",
 			"must end with a lowercase alphanumeric character",
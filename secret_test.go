@@ -16,10 +16,10 @@ func TestCreateSecretYAML(t *testing.T) {
 		"metadata:\n" +
 		"    name: example-secret\n"
 	secretName := "example-secret"
-	metadata := map[string]*string{
-		"name": &secretName,
+	metadata := ObjectMeta{
+		Name: secretName,
 	}
-	got, err := createSecretYAML(metadata, map[string]string{"A": "B"})
+	got, err := createSecretYAML(metadata, "", map[string]string{"A": "B"})
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err)
 	}
@@ -61,3 +61,26 @@ func TestValidateSecretName(t *testing.T) {
 		t.Errorf("Did not expect error string containing a number: %s", err)
 	}
 }
+
+func TestValidateSecretKey(t *testing.T) {
+	tests := []struct {
+		key         string
+		expectError bool
+	}{
+		{"PASSWORD", false},
+		{"tls.crt", false},
+		{".dockerconfigjson", false},
+		{"a-b_c.d", false},
+		{"", true},
+		{".", true},
+		{"..", true},
+		{"bad key", true},
+		{"a/b", true},
+		{strings.Repeat("a", 254), true},
+	}
+	for _, test := range tests {
+		if err := validateSecretKey(test.key); (err != nil) != test.expectError {
+			t.Errorf("Expected error %t for key '%s', got: %v", test.expectError, test.key, err)
+		}
+	}
+}
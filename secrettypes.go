@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// SecretTypeBuilder is a guided flow for creating one of the Kubernetes
+// built-in Secret types, prompting for the fields that make up the Secret
+// rather than for its raw keys.
+type SecretTypeBuilder struct {
+	Name   string
+	Type   string
+	Fields []PromptField
+	// Build validates the entered fields and returns any Secret keys which
+	// are computed from them, in addition to fields stored under their own key.
+	Build func(fields map[string]string) (map[string]string, error)
+}
+
+var secretTypeBuilders = []SecretTypeBuilder{
+	{
+		Name: "tls",
+		Type: "kubernetes.io/tls",
+		Fields: []PromptField{
+			{Name: "cert", Description: "path to PEM encoded certificate (chain)", File: true, Key: "tls.crt"},
+			{Name: "key", Description: "path to PEM encoded private key", File: true, Key: "tls.key"},
+		},
+		Build: buildTLSSecret,
+	},
+	{
+		Name: "dockerconfigjson",
+		Type: "kubernetes.io/dockerconfigjson",
+		Fields: []PromptField{
+			{Name: "server", Description: "registry server, e.g. ghcr.io"},
+			{Name: "username", Description: "registry username"},
+			{Name: "password", Description: "registry password or token", Hidden: true},
+			{Name: "email", Description: "email (optional)", Optional: true},
+		},
+		Build: buildDockerConfigJSONSecret,
+	},
+	{
+		Name: "basic-auth",
+		Type: "kubernetes.io/basic-auth",
+		Fields: []PromptField{
+			{Name: "username", Description: "username", Optional: true, Key: "username"},
+			{Name: "password", Description: "password", Optional: true, Key: "password", Hidden: true},
+		},
+		Build: buildBasicAuthSecret,
+	},
+	{
+		Name: "ssh-auth",
+		Type: "kubernetes.io/ssh-auth",
+		Fields: []PromptField{
+			{Name: "private-key", Description: "path to SSH private key", File: true, Key: "ssh-privatekey"},
+		},
+		Build: buildSSHAuthSecret,
+	},
+}
+
+// secretTypeBuilderFor finds the builder for a short name such as "tls" or a
+// full Secret type such as "kubernetes.io/tls".
+func secretTypeBuilderFor(secretType string) (builder SecretTypeBuilder, exists bool) {
+	for _, builder := range secretTypeBuilders {
+		if secretType == builder.Name || secretType == builder.Type {
+			return builder, true
+		}
+	}
+	return
+}
+
+func secretTypeBuilderNames() []string {
+	names := []string{}
+	for _, builder := range secretTypeBuilders {
+		names = append(names, builder.Name)
+	}
+	return names
+}
+
+func buildTLSSecret(fields map[string]string) (map[string]string, error) {
+	_, err := tls.X509KeyPair([]byte(fields["cert"]), []byte(fields["key"]))
+	if err != nil {
+		return nil, fmt.Errorf("TLS cert and key are not a valid pair: %s", err)
+	}
+	return nil, nil
+}
+
+func buildDockerConfigJSONSecret(fields map[string]string) (map[string]string, error) {
+	server := strings.TrimSpace(fields["server"])
+	if strings.ContainsAny(server, " \t") {
+		return nil, fmt.Errorf("registry server cannot contain spaces: %s", server)
+	}
+	if strings.Contains(fields["username"], ":") {
+		return nil, fmt.Errorf("registry username cannot contain ':'")
+	}
+	type dockerAuth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Email    string `json:"email,omitempty"`
+		Auth     string `json:"auth"`
+	}
+	config := struct {
+		Auths map[string]dockerAuth `json:"auths"`
+	}{
+		Auths: map[string]dockerAuth{
+			server: {
+				Username: fields["username"],
+				Password: fields["password"],
+				Email:    fields["email"],
+				Auth:     base64.StdEncoding.EncodeToString([]byte(fields["username"] + ":" + fields["password"])),
+			},
+		},
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{".dockerconfigjson": string(configJSON)}, nil
+}
+
+func buildBasicAuthSecret(fields map[string]string) (map[string]string, error) {
+	if fields["username"] == "" && fields["password"] == "" {
+		return nil, fmt.Errorf("at least one of username or password is required")
+	}
+	return nil, nil
+}
+
+func buildSSHAuthSecret(fields map[string]string) (map[string]string, error) {
+	block, _ := pem.Decode([]byte(fields["private-key"]))
+	if block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return nil, fmt.Errorf("SSH private key is not a PEM encoded private key")
+	}
+	if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+		return nil, fmt.Errorf("SSH private key must not be passphrase protected")
+	}
+	return nil, nil
+}
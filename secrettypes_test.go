@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testKeyPairPEM(t *testing.T) (certPEM string, keyPEM string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return
+}
+
+func TestBuildTLSSecret(t *testing.T) {
+	certPEM, keyPEM := testKeyPairPEM(t)
+	_, otherKeyPEM := testKeyPairPEM(t)
+	if _, err := buildTLSSecret(map[string]string{"cert": certPEM, "key": keyPEM}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := buildTLSSecret(map[string]string{"cert": certPEM, "key": otherKeyPEM}); err == nil {
+		t.Errorf("Expected error when key does not match cert")
+	}
+}
+
+func TestBuildDockerConfigJSONSecret(t *testing.T) {
+	// kubectl create secret docker-registry example --docker-server=ghcr.io \
+	//   --docker-username=user --docker-password=pass -o yaml
+	expect := `{"auths":{"ghcr.io":{"username":"user","password":"pass","auth":"dXNlcjpwYXNz"}}}`
+	got, err := buildDockerConfigJSONSecret(map[string]string{
+		"server":   "ghcr.io",
+		"username": "user",
+		"password": "pass",
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if got[".dockerconfigjson"] != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, got[".dockerconfigjson"])
+	}
+	if _, err = buildDockerConfigJSONSecret(map[string]string{"server": "ghcr.io", "username": "a:b"}); err == nil {
+		t.Errorf("Expected error for username containing ':'")
+	}
+}
+
+func TestBuildBasicAuthAndSSHAuthSecret(t *testing.T) {
+	if _, err := buildBasicAuthSecret(map[string]string{}); err == nil {
+		t.Errorf("Expected error when neither username nor password given")
+	}
+	if _, err := buildBasicAuthSecret(map[string]string{"password": "secret"}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	_, keyPEM := testKeyPairPEM(t)
+	if _, err := buildSSHAuthSecret(map[string]string{"private-key": keyPEM}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := buildSSHAuthSecret(map[string]string{"private-key": "ssh-ed25519 AAAA"}); err == nil {
+		t.Errorf("Expected error for public key")
+	}
+}
+
+func TestSecretTypeBuilderFor(t *testing.T) {
+	for _, secretType := range []string{"tls", "kubernetes.io/tls"} {
+		builder, exists := secretTypeBuilderFor(secretType)
+		if !exists || builder.Type != "kubernetes.io/tls" {
+			t.Errorf("Expected tls builder for '%s'", secretType)
+		}
+	}
+	if _, exists := secretTypeBuilderFor("Opaque"); exists {
+		t.Errorf("Did not expect builder for Opaque")
+	}
+}
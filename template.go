@@ -0,0 +1,538 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// SealedSecretTemplate is a file of one or more SealedSecrets wrapped in Helm
+// conditions, kept as its original text so it can be edited without
+// rewriting anything other than the entries that change.
+//
+// A file contains one or more blocks, each gated on a set of environments:
+// either a single `if ... end`, or an `if ... else if ... end` chain. Each
+// block contains one or more YAML documents separated by `---`.
+type SealedSecretTemplate struct {
+	Filename    string
+	Environment string
+	Blocks      []*TemplateBlock
+	// Document is the SealedSecret being worked on. It is set when the
+	// environment from the filename selects a block containing a single
+	// document, otherwise it must be chosen with Select.
+	Document *yamlDocument
+	parts    []templatePart
+	project  ProjectConfig
+}
+
+type TemplateBlock struct {
+	Environments []string
+	Documents    []*yamlDocument
+	// Line is the line of the block's Helm condition, and DocumentLines the
+	// first line of each document, as parsed.
+	Line          int
+	DocumentLines []int
+}
+
+type templatePartKind int
+
+const (
+	templatePart_Raw templatePartKind = iota
+	templatePart_Document
+	templatePart_If
+	templatePart_ElseIf
+	templatePart_End
+)
+
+// templatePart is a piece of the original file: either raw text (Helm
+// directives, separators, blank lines) or a YAML document.
+type templatePart struct {
+	kind     templatePartKind
+	raw      string
+	document *yamlDocument
+}
+
+func (p templatePart) String() string {
+	if p.kind == templatePart_Document {
+		return p.document.String()
+	}
+	return p.raw
+}
+
+func parseSealedSecretTemplate(filename string, environment string, template string, project ProjectConfig) (t *SealedSecretTemplate, err error) {
+	t, err = parseTemplateFile(filename, environment, template, project)
+	if err != nil {
+		return
+	}
+	condition := project.HelmCondition(environment)
+	target := t.Block(condition.Environments)
+	if target == nil {
+		found := []string{}
+		for _, b := range t.Blocks {
+			found = append(found, strings.Join(b.Environments, ", "))
+		}
+		templateErr := &TemplateError{
+			Filename: filename,
+			Line:     t.Blocks[0].Line,
+			Message: fmt.Sprintf("template is gated on environments %s but filename expects %s",
+				strings.Join(found, "; "), strings.Join(condition.Environments, ", ")),
+			Suggestion: fmt.Sprintf("add a block with: %s", condition.FirstLine()),
+		}
+		if len(t.Blocks) == 1 && len(t.Blocks[0].Environments) == 1 && len(condition.Environments) == 1 {
+			templateErr.Message = fmt.Sprintf("environment in wrapper is `%s` but filename says `%s`",
+				t.Blocks[0].Environments[0], environment)
+			templateErr.Suggestion = environmentMismatchSuggestion(
+				filename, t.Blocks[0].Environments[0], t.Blocks[0].Line, project, condition)
+		}
+		return nil, templateErr
+	}
+	if len(target.Documents) == 1 {
+		t.Document = target.Documents[0]
+	}
+	return
+}
+
+// parseTemplateFile parses the blocks and documents of a file without
+// requiring a block for the given environment.
+func parseTemplateFile(filename string, environment string, template string, project ProjectConfig) (t *SealedSecretTemplate, err error) {
+	condition := project.HelmCondition(environment)
+	t = &SealedSecretTemplate{
+		Filename:    filename,
+		Environment: environment,
+		project:     project,
+	}
+	lines := strings.SplitAfter(template, "\n")
+	var block *TemplateBlock
+	bodyStart := 0
+	inChain := false
+	closeBlock := func(end int) error {
+		if block == nil {
+			return nil
+		}
+		return t.addDocuments(block, lines, bodyStart, end)
+	}
+
+	nonEmpty := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			nonEmpty++
+		}
+	}
+	if nonEmpty < 3 {
+		return nil, &TemplateError{
+			Filename: filename,
+			Message:  "template file needs to contain at least 3 lines",
+			Suggestion: fmt.Sprintf("wrap the SealedSecret in:\n%s\n...\n%s",
+				condition.FirstLine(), lastLineTemplate),
+		}
+	}
+
+	for i, line := range lines {
+		lineNumber := i + 1
+		trimmed := strings.TrimSpace(line)
+		kind, expression, isDirective := parseTemplateDirective(trimmed)
+		// Within a block only actions at the start of a line wrap the
+		// documents; indented ones, such as in template data, are YAML.
+		isAction := strings.HasPrefix(trimmed, "{{") && (!inChain || strings.HasPrefix(line, "{{"))
+		if !isAction || (isDirective && kind == templatePart_Raw && inChain) {
+			if inChain {
+				continue
+			}
+			if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") || isDirective {
+				t.parts = append(t.parts, templatePart{kind: templatePart_Raw, raw: line})
+				continue
+			}
+			templateErr := &TemplateError{
+				Filename:   filename,
+				Line:       lineNumber,
+				Message:    fmt.Sprintf("content outside of a Helm condition: %s", trimmed),
+				Suggestion: "move it inside an if block, or remove it",
+			}
+			if len(t.Blocks) == 0 {
+				templateErr.Message = fmt.Sprintf("first line of template not in expected format: %s", trimmed)
+				templateErr.Suggestion = fmt.Sprintf("the file should start with: %s", condition.FirstLine())
+			}
+			return nil, templateErr
+		}
+		switch {
+		case isDirective && kind == templatePart_If && !inChain:
+			block, err = t.parseBlock(expression, condition, lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			inChain = true
+			bodyStart = i + 1
+			t.parts = append(t.parts, templatePart{kind: templatePart_If, raw: line})
+		case isDirective && kind == templatePart_ElseIf && inChain:
+			if err = closeBlock(i); err != nil {
+				return nil, err
+			}
+			block, err = t.parseBlock(expression, condition, lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			bodyStart = i + 1
+			t.parts = append(t.parts, templatePart{kind: templatePart_ElseIf, raw: line})
+		case isDirective && kind == templatePart_End && inChain:
+			if err = closeBlock(i); err != nil {
+				return nil, err
+			}
+			block = nil
+			inChain = false
+			t.parts = append(t.parts, templatePart{kind: templatePart_End, raw: line})
+		case !inChain && len(t.Blocks) == 0:
+			return nil, &TemplateError{
+				Filename:   filename,
+				Line:       lineNumber,
+				Message:    fmt.Sprintf("first line of template not in expected format: %s", trimmed),
+				Suggestion: fmt.Sprintf("the file should start with: %s", condition.FirstLine()),
+			}
+		case inChain && strings.Trim(trimmed, "{}- ") == "else":
+			return nil, &TemplateError{
+				Filename:   filename,
+				Line:       lineNumber,
+				Message:    "`else` without a condition is not supported",
+				Suggestion: fmt.Sprintf("use an `else if` for each environment, e.g. %s", strings.Replace(condition.FirstLine(), "{{- if ", "{{- else if ", 1)),
+			}
+		case inChain:
+			return nil, &TemplateError{
+				Filename: filename,
+				Line:     lineNumber,
+				Message:  fmt.Sprintf("unsupported Helm directive: %s", trimmed),
+				Suggestion: fmt.Sprintf("only `if`, `else if` and `end` are supported around SealedSecrets, e.g. %s",
+					lastLineTemplate),
+			}
+		default:
+			return nil, &TemplateError{
+				Filename:   filename,
+				Line:       lineNumber,
+				Message:    fmt.Sprintf("unexpected Helm directive outside of a condition: %s", trimmed),
+				Suggestion: "remove it, or move it inside an if block",
+			}
+		}
+	}
+	if inChain {
+		last := len(lines)
+		for last > 1 && strings.TrimSpace(lines[last-1]) == "" {
+			last--
+		}
+		return nil, &TemplateError{
+			Filename:   filename,
+			Line:       last,
+			Message:    fmt.Sprintf("last line of template not in expected format, missing %s", lastLineTemplate),
+			Suggestion: fmt.Sprintf("add %s after line %d", lastLineTemplate, last),
+		}
+	}
+	if len(t.Blocks) == 0 {
+		return nil, &TemplateError{
+			Filename:   filename,
+			Message:    "template file does not contain a Helm condition",
+			Suggestion: fmt.Sprintf("the file should start with: %s", condition.FirstLine()),
+		}
+	}
+	return t, nil
+}
+
+func (t *SealedSecretTemplate) parseBlock(expression string, condition HelmCondition, line int) (*TemplateBlock, error) {
+	environments, err := parseHelmCondition(expression, condition.ValuesPath, condition.Shape)
+	if err != nil {
+		return nil, &TemplateError{
+			Filename:   t.Filename,
+			Line:       line,
+			Message:    err.Error(),
+			Suggestion: helmConditionSuggestion(expression, condition),
+		}
+	}
+	for _, b := range t.Blocks {
+		for _, environment := range environments {
+			if contains(b.Environments, environment) {
+				return nil, &TemplateError{
+					Filename:   t.Filename,
+					Line:       line,
+					Message:    fmt.Sprintf("more than one block for environment '%s'", environment),
+					Suggestion: fmt.Sprintf("merge it into the block on line %d", b.Line),
+				}
+			}
+		}
+	}
+	block := &TemplateBlock{Environments: environments, Line: line}
+	t.Blocks = append(t.Blocks, block)
+	return block, nil
+}
+
+// addDocuments splits the body of a block, lines[start:end], into its YAML
+// documents. Helm comment lines are kept as they are between documents.
+func (t *SealedSecretTemplate) addDocuments(block *TemplateBlock, lines []string, start int, end int) error {
+	document := []string{}
+	documentStart := start
+	flush := func(separator string) error {
+		text := strings.Join(document, "")
+		document = []string{}
+		if yamlIsEmpty(text) {
+			t.parts = append(t.parts, templatePart{kind: templatePart_Raw, raw: text + separator})
+			return nil
+		}
+		doc, err := parseYAMLDocument(text)
+		if err != nil {
+			templateErr := yamlTemplateError(t.Filename, documentStart+1, lines, err)
+			if templateErr.Line == documentStart+1 && err.Error() == "expected YAML document to contain a mapping" {
+				templateErr.Message = "expected a SealedSecret but the YAML is not a mapping"
+			}
+			return templateErr
+		}
+		block.Documents = append(block.Documents, doc)
+		block.DocumentLines = append(block.DocumentLines, documentStart+1)
+		t.parts = append(t.parts, templatePart{kind: templatePart_Document, document: doc})
+		if separator != "" {
+			t.parts = append(t.parts, templatePart{kind: templatePart_Raw, raw: separator})
+		}
+		return nil
+	}
+	for i := start; i < end; i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		kind, _, isDirective := parseTemplateDirective(trimmed)
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- ") || (isDirective && kind == templatePart_Raw) {
+			if err := flush(line); err != nil {
+				return err
+			}
+			documentStart = i + 1
+			continue
+		}
+		if len(document) == 0 {
+			documentStart = i
+		}
+		document = append(document, line)
+	}
+	if err := flush(""); err != nil {
+		return err
+	}
+	if len(block.Documents) == 0 {
+		return &TemplateError{
+			Filename:   t.Filename,
+			Line:       block.Line,
+			Message:    fmt.Sprintf("block for %s does not contain a SealedSecret", strings.Join(block.Environments, ", ")),
+			Suggestion: "add a SealedSecret, or remove the block",
+		}
+	}
+	return nil
+}
+
+func yamlIsEmpty(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Block returns the block gated on exactly the given environments.
+func (t *SealedSecretTemplate) Block(environments []string) *TemplateBlock {
+	for _, block := range t.Blocks {
+		if (HelmCondition{Environments: block.Environments}).Matches(environments) {
+			return block
+		}
+	}
+	return nil
+}
+
+// Select sets the document being worked on to the one named name within the
+// block for the filename's environment.
+func (t *SealedSecretTemplate) Select(name string) error {
+	block := t.Block(t.project.Environments(t.Environment))
+	if block == nil {
+		return fmt.Errorf("template (%s) has no block for environment %s", t.Filename, t.Environment)
+	}
+	for _, document := range block.Documents {
+		if documentName, _ := document.Get([]string{"metadata"}, "name"); documentName == name {
+			t.Document = document
+			return nil
+		}
+	}
+	return fmt.Errorf("template (%s) has no SealedSecret named '%s' for environment %s", t.Filename, name, t.Environment)
+}
+
+// Names returns the metadata.name of each document in the block for the
+// filename's environment.
+func (t *SealedSecretTemplate) Names() (names []string) {
+	block := t.Block(t.project.Environments(t.Environment))
+	if block == nil {
+		return
+	}
+	for _, document := range block.Documents {
+		name, _ := document.Get([]string{"metadata"}, "name")
+		names = append(names, name)
+	}
+	return
+}
+
+func (t *SealedSecretTemplate) SealedSecret() (sealedSecret SealedSecret, err error) {
+	if t.Document == nil {
+		err = fmt.Errorf("template file %s contains more than one SealedSecret for environment %s: %s",
+			t.Filename, t.Environment, strings.Join(t.Names(), ", "))
+		return
+	}
+	sealedSecret, err = decodeSealedSecret(t.Document)
+	if err != nil {
+		err = fmt.Errorf("template file %s does not contain a valid SealedSecret: %s", t.Filename, err)
+		return
+	}
+	sealedSecret.Environment = t.Environment
+	return
+}
+
+// decodeSealedSecret decodes a document of a template file, unescaping its
+// template data.
+func decodeSealedSecret(document *yamlDocument) (sealedSecret SealedSecret, err error) {
+	err = document.Decode(&sealedSecret)
+	sealedSecret.Spec.Template.Data = mapTemplateData(sealedSecret.Spec.Template.Data, unescapeTemplateData)
+	return
+}
+
+// SealedSecrets returns every SealedSecret in the block for the filename's
+// environment, in the order they appear.
+func (t *SealedSecretTemplate) SealedSecrets() (sealedSecrets []SealedSecret, err error) {
+	block := t.Block(t.project.Environments(t.Environment))
+	if block == nil {
+		err = fmt.Errorf("template (%s) has no block for environment %s", t.Filename, t.Environment)
+		return
+	}
+	for i, document := range block.Documents {
+		var sealedSecret SealedSecret
+		sealedSecret, err = decodeSealedSecret(document)
+		if err != nil {
+			err = fmt.Errorf("%s:%d: not a valid SealedSecret: %s", t.Filename, block.DocumentLines[i], err)
+			return
+		}
+		sealedSecret.Environment = t.Environment
+		sealedSecrets = append(sealedSecrets, sealedSecret)
+	}
+	return
+}
+
+// RecordRotation sets the annotations recording when and with which cert the
+// selected document was sealed.
+func (t *SealedSecretTemplate) RecordRotation(now time.Time, certFingerprint string) error {
+	path := []string{"metadata", "annotations"}
+	err := t.Document.EnsureMapping(path...)
+	annotations := rotationAnnotations(now, certFingerprint)
+	for _, k := range sortedKeys(annotations) {
+		if err == nil {
+			err = t.Document.Set(path, k, annotations[k])
+		}
+	}
+	return err
+}
+
+// Append adds a SealedSecret to the file, either as another document in the
+// block for its environment or as a new `else if` block if there is none.
+func (t *SealedSecretTemplate) Append(sealedSecret SealedSecret) error {
+	for _, name := range t.Names() {
+		if name == sealedSecret.Metadata.Name {
+			return fmt.Errorf("template file %s already contains a SealedSecret named '%s' for environment %s",
+				t.Filename, name, t.Environment)
+		}
+	}
+	manifest, err := sealedSecret.ToYAML(t.project.Output)
+	if err != nil {
+		return err
+	}
+	document, err := parseYAMLDocument(string(manifest))
+	if err != nil {
+		return err
+	}
+	insert := []templatePart{}
+	at := -1
+	if block := t.Block(t.project.Environments(t.Environment)); block != nil {
+		last := block.Documents[len(block.Documents)-1]
+		for i, part := range t.parts {
+			if part.document == last {
+				at = i + 1
+			}
+		}
+		if !strings.HasSuffix(last.String(), "\n") {
+			last.source += "\n"
+		}
+		insert = append(insert, templatePart{kind: templatePart_Raw, raw: "---\n"})
+		block.Documents = append(block.Documents, document)
+	} else {
+		for i, part := range t.parts {
+			if part.kind == templatePart_End {
+				at = i
+			}
+		}
+		condition := t.project.HelmCondition(t.Environment)
+		elseIf := strings.Replace(condition.FirstLine(), "{{- if ", "{{- else if ", 1)
+		insert = append(insert, templatePart{kind: templatePart_ElseIf, raw: elseIf + "\n"})
+		t.Blocks = append(t.Blocks, &TemplateBlock{
+			Environments: condition.Environments,
+			Documents:    []*yamlDocument{document},
+		})
+	}
+	if at < 0 {
+		return fmt.Errorf("cannot find where to add SealedSecret in template file %s", t.Filename)
+	}
+	insert = append(insert, templatePart{kind: templatePart_Document, document: document})
+	t.parts = append(t.parts[:at], append(insert, t.parts[at:]...)...)
+	t.Document = document
+	return nil
+}
+
+// SetEncryptedData replaces (or adds) the given entries of spec.encryptedData.
+func (t *SealedSecretTemplate) SetEncryptedData(encryptedData map[string]string) error {
+	if t.Document.Mapping("spec", "encryptedData") == nil {
+		return fmt.Errorf("template file %s has no spec.encryptedData mapping", t.Filename)
+	}
+	for _, key := range sortedKeys(encryptedData) {
+		err := t.Document.Set([]string{"spec", "encryptedData"}, key, encryptedData[key])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyOutput applies the project's null and key ordering settings to the
+// document being worked on, and with reformat also its indentation.
+func (t *SealedSecretTemplate) ApplyOutput(reformat bool) error {
+	err := t.project.Output.apply(t.Document)
+	if err == nil && reformat {
+		err = t.Document.Reformat(t.project.Output.Indent)
+	}
+	return err
+}
+
+func (t *SealedSecretTemplate) String() string {
+	out := strings.Builder{}
+	for _, part := range t.parts {
+		out.WriteString(part.String())
+	}
+	return t.project.Output.finish(out.String())
+}
+
+// Write replaces the content of f with the template.
+func (t *SealedSecretTemplate) Write(f *os.File) error {
+	err := f.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(t.String())
+	return err
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSealedSecretTemplateRoundTrip(t *testing.T) {
+	template := "" +
+		"{{- if eq .Values.environment \"testing\" }}\n" +
+		"# Managed by kubesealplus\n" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"  name: example-secret\n" +
+		"  namespace: example\n" +
+		"  annotations:\n" +
+		"    sealedsecrets.bitnami.com/managed: \"true\"\n" +
+		"spec:\n" +
+		"  encryptedData:\n" +
+		"    PASSWORD: AgBy3i4OJSWK+PiTySYZZA==\n" +
+		"    USERNAME: AgAKAoiQm7xFtFqSJ8TqDE==\n" +
+		"  template:\n" +
+		"    type: kubernetes.io/basic-auth\n" +
+		"    metadata:\n" +
+		"      name: example-secret\n" +
+		"      namespace: example\n" +
+		"{{- end }}\n"
+	filename := "templates/secret-example.testing.yaml"
+	tmpl, err := parseSealedSecretTemplate(filename, "testing", template, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tmpl.String() != template {
+		t.Errorf("Expected unmodified template to round-trip.\nExpected:\n%s\nGot:\n%s", template, tmpl.String())
+	}
+	err = tmpl.SetEncryptedData(map[string]string{"PASSWORD": "AgNEWCIPHERTEXT=="})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := "" +
+		"{{- if eq .Values.environment \"testing\" }}\n" +
+		"# Managed by kubesealplus\n" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"  name: example-secret\n" +
+		"  namespace: example\n" +
+		"  annotations:\n" +
+		"    sealedsecrets.bitnami.com/managed: \"true\"\n" +
+		"spec:\n" +
+		"  encryptedData:\n" +
+		"    PASSWORD: AgNEWCIPHERTEXT==\n" +
+		"    USERNAME: AgAKAoiQm7xFtFqSJ8TqDE==\n" +
+		"  template:\n" +
+		"    type: kubernetes.io/basic-auth\n" +
+		"    metadata:\n" +
+		"      name: example-secret\n" +
+		"      namespace: example\n" +
+		"{{- end }}\n"
+	if tmpl.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, tmpl.String())
+	}
+}
+
+const testMultiTemplate = "" +
+	"{{- if eq .Values.environment \"production\" }}\n" +
+	"apiVersion: bitnami.com/v1alpha1\n" +
+	"kind: SealedSecret\n" +
+	"metadata:\n" +
+	"  name: db-secret\n" +
+	"  namespace: example\n" +
+	"spec:\n" +
+	"  encryptedData:\n" +
+	"    PASSWORD: AgProductionDb==\n" +
+	"---\n" +
+	"apiVersion: bitnami.com/v1alpha1\n" +
+	"kind: SealedSecret\n" +
+	"metadata:\n" +
+	"  name: api-secret\n" +
+	"  namespace: example\n" +
+	"spec:\n" +
+	"  encryptedData:\n" +
+	"    TOKEN: AgProductionApi==\n" +
+	"{{- else if eq .Values.environment \"staging\" }}\n" +
+	"apiVersion: bitnami.com/v1alpha1\n" +
+	"kind: SealedSecret\n" +
+	"metadata:\n" +
+	"  name: db-secret\n" +
+	"  namespace: example\n" +
+	"spec:\n" +
+	"  encryptedData:\n" +
+	"    PASSWORD: AgStagingDb==\n" +
+	"{{- end }}\n"
+
+func TestSealedSecretTemplateMultiple(t *testing.T) {
+	filename := "templates/secret-db.staging.yaml"
+	tmpl, err := parseSealedSecretTemplate(filename, "staging", testMultiTemplate, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(tmpl.Blocks) != 2 || len(tmpl.Blocks[0].Documents) != 2 || len(tmpl.Blocks[1].Documents) != 1 {
+		t.Fatalf("Expected 2 blocks with 2 and 1 documents, got %d blocks", len(tmpl.Blocks))
+	}
+	if tmpl.String() != testMultiTemplate {
+		t.Errorf("Expected unmodified template to round-trip.\nGot:\n%s", tmpl.String())
+	}
+	err = tmpl.SetEncryptedData(map[string]string{"PASSWORD": "AgNewStagingDb=="})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := strings.Replace(testMultiTemplate, "AgStagingDb==", "AgNewStagingDb==", 1)
+	if tmpl.String() != expect {
+		t.Errorf("Expected only the staging block to change.\nExpected:\n%s\nGot:\n%s", expect, tmpl.String())
+	}
+
+	tmpl, err = parseSealedSecretTemplate(filename, "production", testMultiTemplate, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tmpl.Document != nil {
+		t.Errorf("Expected no document to be selected for a block with multiple documents")
+	}
+	if _, err = tmpl.SealedSecret(); err == nil {
+		t.Errorf("Expected error when no document is selected")
+	}
+	if err = tmpl.Select("api-secret"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sealedSecret, err := tmpl.SealedSecret()
+	if err != nil || sealedSecret.Spec.EncryptedData["TOKEN"] != "AgProductionApi==" {
+		t.Errorf("Expected api-secret to be selected, got:\n%+v (%v)", sealedSecret, err)
+	}
+
+	if _, err = parseSealedSecretTemplate(filename, "qa", testMultiTemplate, ProjectConfigDefault()); err == nil {
+		t.Errorf("Expected error for environment with no block")
+	}
+}
+
+func TestSealedSecretTemplateTemplateData(t *testing.T) {
+	template := "" +
+		"{{- if eq .Values.environment \"testing\" }}\n" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"    name: example-secret\n" +
+		"    namespace: example\n" +
+		"spec:\n" +
+		"    encryptedData:\n" +
+		"        URL: AgBy3i4OJSWK\n" +
+		"    template:\n" +
+		"        data:\n" +
+		"            url: |\n" +
+		"                {{ .URL }}\n" +
+		"        metadata:\n" +
+		"            name: example-secret\n" +
+		"{{- end }}\n"
+	tmpl, err := parseSealedSecretTemplate("templates/secret-example.testing.yaml", "testing", template, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error for template data in a block scalar: %s", err)
+	}
+	config := "{{ .URL }}\n{{- if .URL }}\nset\n{{- end }}\n"
+	if err = tmpl.SetTemplateData(map[string]*string{"config": &config}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	written := tmpl.String()
+	tmpl, err = parseSealedSecretTemplate("templates/secret-example.testing.yaml", "testing", written, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error parsing written template data: %s\n%s", err, written)
+	}
+	sealedSecret, err := tmpl.SealedSecret()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	data := *sealedSecret.Spec.Template.Data
+	if data["url"] != "{{ .URL }}\n" || data["config"] != config {
+		t.Errorf("Expected template data to be read back, got:\n%q", data)
+	}
+}
+
+func TestSealedSecretTemplateAppend(t *testing.T) {
+	filename := "templates/secret-db.qa.yaml"
+	tmpl, err := parseTemplateFile(filename, "qa", testMultiTemplate, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sealedSecret := SealedSecret{}
+	sealedSecret.Init("db-secret", "example")
+	sealedSecret.Spec.EncryptedData = map[string]string{"PASSWORD": "AgQaDb=="}
+	if err = tmpl.Append(sealedSecret); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	tmpl, err = parseSealedSecretTemplate(filename, "qa", tmpl.String(), ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error parsing appended template: %s", err)
+	}
+	if !strings.Contains(tmpl.String(), "{{- else if eq .Values.environment \"qa\" }}\n") {
+		t.Errorf("Expected new else if block, got:\n%s", tmpl.String())
+	}
+	if len(tmpl.Blocks) != 3 {
+		t.Errorf("Expected 3 blocks, got %d", len(tmpl.Blocks))
+	}
+
+	tmpl, _ = parseSealedSecretTemplate(filename, "staging", testMultiTemplate, ProjectConfigDefault())
+	if err = tmpl.Append(sealedSecret); err == nil {
+		t.Errorf("Expected error appending a duplicate name")
+	}
+	sealedSecret.Init("api-secret", "example")
+	if err = tmpl.Append(sealedSecret); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	tmpl, err = parseSealedSecretTemplate(filename, "staging", tmpl.String(), ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error parsing appended template: %s", err)
+	}
+	if names := tmpl.Names(); len(names) != 2 || names[1] != "api-secret" {
+		t.Errorf("Expected db-secret and api-secret for staging, got: %v", names)
+	}
+}
+
+func TestSealedSecretTemplateRecordRotation(t *testing.T) {
+	tmpl, err := parseSealedSecretTemplate("templates/secret-db.staging.yaml", "staging", testMultiTemplate, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = tmpl.RecordRotation(time.Date(2023, 3, 1, 12, 0, 0, 0, time.FixedZone("AEDT", 11*60*60)), "sha256:abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sealedSecrets, err := tmpl.SealedSecrets()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if got := sealedSecrets[0].Metadata.Annotations[SealedSecretAnnotation_LastRotated]; got != "2023-03-01T01:00:00Z" {
+		t.Errorf("Expected last rotated annotation in UTC, got '%s'", got)
+	}
+	if got := sealedSecrets[0].Metadata.Annotations[SealedSecretAnnotation_CertFingerprint]; got != "sha256:abc" {
+		t.Errorf("Expected cert fingerprint annotation, got '%s'", got)
+	}
+}
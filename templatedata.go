@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template data is written into a file rendered by Helm, so its actions are
+// escaped to render as themselves, leaving them for the controller to run.
+var (
+	templateDataEscaper   = strings.NewReplacer("{{", `{{"{{"}}`, "}}", `{{"}}"}}`)
+	templateDataUnescaper = strings.NewReplacer(`{{"{{"}}`, "{{", `{{"}}"}}`, "}}")
+)
+
+// escapeTemplateData escapes a template data entry for Helm, as written to
+// the file.
+func escapeTemplateData(text string) string {
+	return templateDataEscaper.Replace(text)
+}
+
+// unescapeTemplateData returns a template data entry read from the file as
+// the template the controller renders. Text which isn't escaped is returned
+// as is.
+func unescapeTemplateData(text string) string {
+	return templateDataUnescaper.Replace(text)
+}
+
+// mapTemplateData returns a copy of template data with escape or unescape
+// applied to each entry, or nil for no template data.
+func mapTemplateData(data *map[string]string, f func(string) string) *map[string]string {
+	if data == nil {
+		return nil
+	}
+	mapped := map[string]string{}
+	for k, v := range *data {
+		mapped[k] = f(v)
+	}
+	return &mapped
+}
+
+// loadTemplateData reads a local YAML file of spec.template.data entries,
+// each a Go template rendered by the Sealed Secrets controller with the
+// decrypted keys. An entry with a null value removes that entry.
+func loadTemplateData(filename string) (entries map[string]*string, err error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		err = fmt.Errorf("cannot read template data file '%s': %s", filename, err)
+		return
+	}
+	err = yaml.Unmarshal(content, &entries)
+	if err != nil {
+		err = fmt.Errorf("cannot parse YAML in template data file '%s': %s", filename, err)
+		return
+	}
+	for key, value := range entries {
+		if value == nil {
+			continue
+		}
+		*value = unescapeTemplateData(*value)
+		if _, err = templateDataReferences(*value); err != nil {
+			err = fmt.Errorf("invalid template for '%s' in template data file '%s': %s", key, filename, err)
+			return
+		}
+	}
+	return
+}
+
+// mergeTemplateData applies entries to existing template data, returning the
+// resulting data, or nil if no entries remain.
+func mergeTemplateData(data map[string]string, entries map[string]*string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range data {
+		merged[k] = v
+	}
+	for k, v := range entries {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = *v
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// validateTemplateData checks every key referenced by the template data is
+// one of the encrypted keys, as the controller fails to render it otherwise.
+func validateTemplateData(data map[string]string, encryptedKeys []string) error {
+	exists := map[string]bool{}
+	for _, key := range encryptedKeys {
+		exists[key] = true
+	}
+	for _, dataKey := range sortedKeys(data) {
+		references, err := templateDataReferences(data[dataKey])
+		if err != nil {
+			return fmt.Errorf("invalid template for spec.template.data '%s': %s", dataKey, err)
+		}
+		for _, key := range references {
+			if !exists[key] {
+				return fmt.Errorf("spec.template.data '%s' references key '%s' which is not in spec.encryptedData", dataKey, key)
+			}
+		}
+	}
+	return nil
+}
+
+// templateDataReferences returns the decrypted keys a template refers to,
+// either as {{ .key }} or {{ index . "key" }}.
+func templateDataReferences(text string) (keys []string, err error) {
+	tree := parse.New("data")
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := map[string]*parse.Tree{}
+	_, err = tree.Parse(unescapeTemplateData(text), "{{", "}}", treeSet)
+	if err != nil {
+		return
+	}
+	found := map[string]bool{}
+	for _, t := range treeSet {
+		templateDataWalk(t.Root, true, found)
+	}
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// templateDataWalk collects referenced keys. Within range and with blocks the
+// dot no longer refers to the decrypted keys, so only $ references count.
+func templateDataWalk(node parse.Node, dotIsRoot bool, found map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			templateDataWalk(child, dotIsRoot, found)
+		}
+	case *parse.ActionNode:
+		templateDataWalk(n.Pipe, dotIsRoot, found)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			templateDataWalk(cmd, dotIsRoot, found)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) == 3 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "index" {
+				if key, ok := n.Args[2].(*parse.StringNode); ok && templateDataIsRoot(n.Args[1], dotIsRoot) {
+					found[key.Text] = true
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			templateDataWalk(arg, dotIsRoot, found)
+		}
+	case *parse.FieldNode:
+		if dotIsRoot {
+			found[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			found[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		templateDataWalk(n.Node, dotIsRoot, found)
+	case *parse.IfNode:
+		templateDataWalk(n.Pipe, dotIsRoot, found)
+		templateDataWalk(n.List, dotIsRoot, found)
+		templateDataWalk(n.ElseList, dotIsRoot, found)
+	case *parse.RangeNode:
+		templateDataWalk(n.Pipe, dotIsRoot, found)
+		templateDataWalk(n.List, false, found)
+		templateDataWalk(n.ElseList, dotIsRoot, found)
+	case *parse.WithNode:
+		templateDataWalk(n.Pipe, dotIsRoot, found)
+		templateDataWalk(n.List, false, found)
+		templateDataWalk(n.ElseList, dotIsRoot, found)
+	case *parse.TemplateNode:
+		templateDataWalk(n.Pipe, dotIsRoot, found)
+	}
+}
+
+func templateDataIsRoot(node parse.Node, dotIsRoot bool) bool {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dotIsRoot
+	case *parse.VariableNode:
+		return len(n.Ident) == 1 && n.Ident[0] == "$"
+	}
+	return false
+}
+
+// SetTemplateData applies entries to spec.template.data, leaving the rest of
+// the template untouched.
+func (t *SealedSecretTemplate) SetTemplateData(entries map[string]*string) error {
+	path := []string{"spec", "template", "data"}
+	keys := []string{}
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var err error
+		if entries[key] == nil {
+			err = t.Document.Delete(path, key)
+		} else {
+			err = t.Document.EnsureMapping(path...)
+			if err == nil {
+				err = t.Document.Set(path, key, escapeTemplateData(*entries[key]))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("cannot update spec.template.data '%s' in %s: %s", key, t.Filename, err)
+		}
+	}
+	return nil
+}
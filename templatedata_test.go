@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTemplateDataReferences(t *testing.T) {
+	tests := []struct {
+		text        string
+		expectKeys  []string
+		expectError bool
+	}{
+		{
+			text:       `{"user": "{{ .username }}", "password": "{{ index . "db.password" }}"}`,
+			expectKeys: []string{"db.password", "username"},
+		},
+		{
+			text:       `{{ range $k, $v := . }}{{ $k }}={{ $v }} {{ $.prefix }}{{ end }}`,
+			expectKeys: []string{"prefix"},
+		},
+		{
+			text:       `{{ .password | b64dec | upper }}`,
+			expectKeys: []string{"password"},
+		},
+		{
+			text:        `{{ .password `,
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		keys, err := templateDataReferences(test.text)
+		if err != nil && !test.expectError {
+			t.Errorf("Unexpected error '%s' from template '%s'", err, test.text)
+		}
+		if err == nil && test.expectError {
+			t.Errorf("Expected error from template '%s'", test.text)
+		}
+		if !reflect.DeepEqual(keys, test.expectKeys) {
+			t.Errorf("Expected keys %v but got %v from template '%s'", test.expectKeys, keys, test.text)
+		}
+	}
+}
+
+func TestValidateTemplateData(t *testing.T) {
+	data := map[string]string{"config.json": `{"password": "{{ .password }}"}`}
+	if err := validateTemplateData(data, []string{"password"}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := validateTemplateData(data, []string{"username"}); err == nil {
+		t.Errorf("Expected error for reference to missing key")
+	}
+}
+
+func TestSetTemplateData(t *testing.T) {
+	template := "" +
+		"{{- if eq .Values.environment \"testing\" }}\n" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"    name: example-secret\n" +
+		"    namespace: example\n" +
+		"spec:\n" +
+		"    encryptedData:\n" +
+		"        password: AgBy3i4OJSWK\n" +
+		"    template:\n" +
+		"        data: null\n" +
+		"        metadata:\n" +
+		"            name: example-secret\n" +
+		"            namespace: example\n" +
+		"{{- end }}\n"
+	tmpl, err := parseSealedSecretTemplate("templates/secret-example.testing.yaml", "testing", template, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	config := "{\n  \"password\": \"{{ .password }}\"\n}\n"
+	url := "postgres://app:{{ .password }}@db/app"
+	err = tmpl.SetTemplateData(map[string]*string{"config.json": &config, "url": &url})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := "" +
+		"{{- if eq .Values.environment \"testing\" }}\n" +
+		"apiVersion: bitnami.com/v1alpha1\n" +
+		"kind: SealedSecret\n" +
+		"metadata:\n" +
+		"    name: example-secret\n" +
+		"    namespace: example\n" +
+		"spec:\n" +
+		"    encryptedData:\n" +
+		"        password: AgBy3i4OJSWK\n" +
+		"    template:\n" +
+		"        data:\n" +
+		"            config.json: |\n" +
+		"                {\n" +
+		"                  \"password\": \"{{\"{{\"}} .password {{\"}}\"}}\"\n" +
+		"                }\n" +
+		"            url: postgres://app:{{\"{{\"}} .password {{\"}}\"}}@db/app\n" +
+		"        metadata:\n" +
+		"            name: example-secret\n" +
+		"            namespace: example\n" +
+		"{{- end }}\n"
+	if tmpl.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, tmpl.String())
+	}
+	rendered := renderHelmTemplate(t, tmpl.String(), "testing")
+	var manifest SealedSecret
+	if err := yaml.Unmarshal([]byte(rendered), &manifest); err != nil {
+		t.Fatalf("Unexpected error parsing rendered template: %s\n%s", err, rendered)
+	}
+	if manifest.Spec.Template.Data == nil || (*manifest.Spec.Template.Data)["config.json"] != config || (*manifest.Spec.Template.Data)["url"] != url {
+		t.Errorf("Expected template data rendered by Helm as written, got:\n%s", rendered)
+	}
+	sealedSecret, err := tmpl.SealedSecret()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if sealedSecret.Spec.Template.Data == nil || (*sealedSecret.Spec.Template.Data)["config.json"] != config {
+		t.Errorf("Expected config.json to round-trip, got:\n%+v", sealedSecret.Spec.Template.Data)
+	}
+
+	err = tmpl.SetTemplateData(map[string]*string{"config.json": nil})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sealedSecret, _ = tmpl.SealedSecret()
+	if _, exists := (*sealedSecret.Spec.Template.Data)["config.json"]; exists {
+		t.Errorf("Expected config.json to be removed")
+	}
+}
+
+// renderHelmTemplate renders a template file as Helm does for an environment.
+func renderHelmTemplate(t *testing.T, text string, environment string) string {
+	tmpl, err := template.New("secret").Parse(text)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing template for Helm: %s", err)
+	}
+	var out bytes.Buffer
+	values := map[string]interface{}{"Values": map[string]interface{}{"environment": environment}}
+	if err := tmpl.Execute(&out, values); err != nil {
+		t.Fatalf("Unexpected error rendering template for Helm: %s", err)
+	}
+	return out.String()
+}
+
+func TestEscapeTemplateData(t *testing.T) {
+	tests := []string{
+		`{"user": "{{ .username }}", "password": "{{ index . "db.password" }}"}`,
+		"{{- range $k, $v := . }}\n{{ $k }}={{ $v }}\n{{- end }}",
+		"no templates",
+	}
+	for _, text := range tests {
+		escaped := escapeTemplateData(text)
+		if got := renderHelmTemplate(t, escaped, "testing"); got != text {
+			t.Errorf("Expected Helm to render %q as %q, got %q", escaped, text, got)
+		}
+		if got := unescapeTemplateData(escaped); got != text {
+			t.Errorf("Expected %q to unescape to %q, got %q", escaped, text, got)
+		}
+		if got := unescapeTemplateData(text); got != text {
+			t.Errorf("Expected unescaped %q to be unchanged, got %q", text, got)
+		}
+	}
+}
+
+func TestSealedSecretToYAMLTemplateData(t *testing.T) {
+	sealedSecret := SealedSecret{}
+	sealedSecret.Init("example-secret", "example")
+	data := map[string]string{"username": "{{ .username }}", "config": "a: '{{ .password }}'\n"}
+	sealedSecret.Spec.Template.Data = &data
+	manifest, err := sealedSecret.ToYAML(ProjectConfigDefault().Output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var rendered SealedSecret
+	if err := yaml.Unmarshal([]byte(renderHelmTemplate(t, string(manifest), "testing")), &rendered); err != nil {
+		t.Fatalf("Unexpected error parsing rendered template: %s\n%s", err, manifest)
+	}
+	if !reflect.DeepEqual(rendered.Spec.Template.Data, &data) {
+		t.Errorf("Expected template data rendered by Helm as %v, got:\n%s", data, manifest)
+	}
+	if (*sealedSecret.Spec.Template.Data)["username"] != "{{ .username }}" {
+		t.Errorf("Expected template data of the SealedSecret to be left unescaped")
+	}
+}
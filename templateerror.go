@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TemplateError is a problem found parsing a template file, pointing at the
+// line it was found on, with a suggested fix where there is one.
+type TemplateError struct {
+	Filename   string
+	Line       int
+	Message    string
+	Suggestion string
+}
+
+func (e *TemplateError) Error() string {
+	location := e.Filename
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", e.Filename, e.Line)
+	}
+	message := fmt.Sprintf("%s: %s", location, e.Message)
+	if e.Suggestion != "" {
+		message += "\n  suggestion: " + e.Suggestion
+	}
+	return message
+}
+
+var templateDirectiveRegexp = regexp.MustCompile(`^\{\{-?\s*(.*?)\s*-?\}\}$`)
+
+// parseTemplateDirective recognises a line holding a single Helm action,
+// tolerating whitespace and with or without the trim markers. It returns
+// the part kind and, for if and else if, the condition expression.
+func parseTemplateDirective(trimmed string) (kind templatePartKind, expression string, ok bool) {
+	match := templateDirectiveRegexp.FindStringSubmatch(trimmed)
+	if match == nil {
+		return
+	}
+	inner := match[1]
+	fields := strings.Fields(inner)
+	switch {
+	case strings.HasPrefix(inner, "/*") && strings.HasSuffix(inner, "*/"):
+		return templatePart_Raw, "", true
+	case len(fields) > 1 && fields[0] == "if":
+		return templatePart_If, strings.TrimSpace(strings.TrimPrefix(inner, "if")), true
+	case len(fields) > 2 && fields[0] == "else" && fields[1] == "if":
+		rest := strings.TrimSpace(strings.TrimPrefix(inner, "else"))
+		return templatePart_ElseIf, strings.TrimSpace(strings.TrimPrefix(rest, "if")), true
+	case inner == "end":
+		return templatePart_End, "", true
+	}
+	return
+}
+
+var yamlErrorLineRegexp = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlTemplateError converts a YAML error within a document starting at
+// firstLine of the file into an error pointing at the line in the file.
+func yamlTemplateError(filename string, firstLine int, lines []string, err error) *TemplateError {
+	templateErr := &TemplateError{
+		Filename: filename,
+		Line:     firstLine,
+		Message:  fmt.Sprintf("invalid YAML: %s", strings.TrimPrefix(err.Error(), "yaml: ")),
+	}
+	match := yamlErrorLineRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return templateErr
+	}
+	line, _ := strconv.Atoi(match[1])
+	templateErr.Line = firstLine + line - 1
+	templateErr.Message = fmt.Sprintf("invalid YAML: %s", match[2])
+	if templateErr.Line >= 1 && templateErr.Line <= len(lines) {
+		text := strings.TrimRight(lines[templateErr.Line-1], "\n")
+		templateErr.Message += fmt.Sprintf("\n  %d | %s", templateErr.Line, text)
+		if strings.Contains(text, "\t") {
+			templateErr.Suggestion = "YAML does not allow tabs for indentation, use spaces instead"
+		}
+	}
+	return templateErr
+}
+
+var helmValuesRefRegexp = regexp.MustCompile(`\$?\.Values(\.[A-Za-z_][A-Za-z0-9_]*)+`)
+
+// helmConditionSuggestion suggests a project config change for a condition
+// that is written in a different form to the one configured.
+func helmConditionSuggestion(expression string, condition HelmCondition) string {
+	if valuesPath := helmValuesRefRegexp.FindString(expression); valuesPath != "" && valuesPath != condition.ValuesPath {
+		return fmt.Sprintf("the condition uses `%s` but `%s` is configured; set `helm.valuesPath: %s` in %s",
+			valuesPath, condition.ValuesPath, valuesPath, ProjectConfigFilename)
+	}
+	fields := strings.Fields(expression)
+	if len(fields) > 0 && fields[0] != condition.Shape && (fields[0] == HelmConditionShape_Or || fields[0] == HelmConditionShape_Has) {
+		return fmt.Sprintf("the condition uses `%s` but `%s` is configured; set `helm.condition: %s` in %s",
+			fields[0], condition.Shape, fields[0], ProjectConfigFilename)
+	}
+	return fmt.Sprintf("expected a condition such as: %s", condition.FirstLine())
+}
+
+// environmentMismatchSuggestion suggests how to fix a file whose wrapper is
+// gated on a different environment to the one in its filename.
+func environmentMismatchSuggestion(filename string, wrapperEnvironment string, line int, project ProjectConfig, condition HelmCondition) string {
+	suggestion := fmt.Sprintf("change line %d to: %s", line, condition.FirstLine())
+	name, _, err := project.Filenames.NameAndEnvironment(filename)
+	if err != nil {
+		return suggestion
+	}
+	renamed, err := project.Filenames.Rename(filename, name, wrapperEnvironment)
+	if err == nil && renamed != filename {
+		suggestion = fmt.Sprintf("rename the file to %s, or %s", renamed, suggestion)
+	}
+	return suggestion
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testErrorBody = `apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+    name: example-secret
+spec:
+    encryptedData:
+        MESSAGE: aGVsbG8gd29ybGQK
+`
+
+func TestParseTemplateDirective(t *testing.T) {
+	tests := []struct {
+		line             string
+		expectOk         bool
+		expectKind       templatePartKind
+		expectExpression string
+	}{
+		{`{{- if eq .Values.environment "testing" }}`, true, templatePart_If, `eq .Values.environment "testing"`},
+		{`{{ if eq .Values.environment "testing" }}`, true, templatePart_If, `eq .Values.environment "testing"`},
+		{`{{-if eq .Values.environment "testing" -}}`, true, templatePart_If, `eq .Values.environment "testing"`},
+		{`{{- else if eq .Values.environment "staging" }}`, true, templatePart_ElseIf, `eq .Values.environment "staging"`},
+		{`{{ end }}`, true, templatePart_End, ""},
+		{`{{- end -}}`, true, templatePart_End, ""},
+		{`{{/* rotated quarterly */}}`, true, templatePart_Raw, ""},
+		{`{{- else }}`, false, 0, ""},
+		{`{{ include "foo" . }}`, false, 0, ""},
+		{`name: example`, false, 0, ""},
+	}
+	for _, test := range tests {
+		kind, expression, ok := parseTemplateDirective(test.line)
+		if ok != test.expectOk {
+			t.Errorf("Expected ok %t for '%s'", test.expectOk, test.line)
+			continue
+		}
+		if kind != test.expectKind || expression != test.expectExpression {
+			t.Errorf("Expected kind %d and expression '%s' for '%s', got %d and '%s'",
+				test.expectKind, test.expectExpression, test.line, kind, expression)
+		}
+	}
+}
+
+func TestParseTemplateTolerant(t *testing.T) {
+	data := "{{ if eq .Values.environment \"testing\" }}\n" + testErrorBody +
+		"{{/* rotated quarterly */}}\n{{ end }}\n"
+	tmpl, err := parseSealedSecretTemplate("secret-example.testing.yaml", "testing", data, ProjectConfigDefault())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tmpl.Document == nil {
+		t.Fatalf("Expected a document")
+	}
+	if tmpl.String() != data {
+		t.Errorf("Expected template unchanged, got:\n%s", tmpl.String())
+	}
+	if tmpl.Blocks[0].Line != 1 || tmpl.Blocks[0].DocumentLines[0] != 2 {
+		t.Errorf("Expected block on line 1 and document on line 2, got %d and %v",
+			tmpl.Blocks[0].Line, tmpl.Blocks[0].DocumentLines)
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		filename         string
+		environment      string
+		data             string
+		expectLine       int
+		expectMessage    string
+		expectSuggestion string
+	}{
+		{
+			name:             "environment mismatch",
+			filename:         "secret-example.production.yaml",
+			environment:      "production",
+			data:             "{{- if eq .Values.environment \"prod\" }}\n" + testErrorBody + "{{- end }}\n",
+			expectLine:       1,
+			expectMessage:    "environment in wrapper is `prod` but filename says `production`",
+			expectSuggestion: "rename the file to secret-example.prod.yaml",
+		},
+		{
+			name:             "values path",
+			filename:         "secret-example.testing.yaml",
+			environment:      "testing",
+			data:             "{{- if eq .Values.global.env \"testing\" }}\n" + testErrorBody + "{{- end }}\n",
+			expectLine:       1,
+			expectSuggestion: "helm.valuesPath: .Values.global.env",
+		},
+		{
+			name:             "missing end",
+			filename:         "secret-example.testing.yaml",
+			environment:      "testing",
+			data:             "{{- if eq .Values.environment \"testing\" }}\n" + testErrorBody + "\n",
+			expectLine:       8,
+			expectMessage:    "missing {{- end }}",
+			expectSuggestion: "add {{- end }} after line 8",
+		},
+		{
+			name:             "else",
+			filename:         "secret-example.testing.yaml",
+			environment:      "testing",
+			data:             "{{- if eq .Values.environment \"testing\" }}\n" + testErrorBody + "{{- else }}\n" + testErrorBody + "{{- end }}\n",
+			expectLine:       9,
+			expectMessage:    "`else` without a condition",
+			expectSuggestion: "{{- else if eq .Values.environment \"testing\" }}",
+		},
+		{
+			name:          "invalid YAML",
+			filename:      "secret-example.testing.yaml",
+			environment:   "testing",
+			data:          "# comment\n{{- if eq .Values.environment \"testing\" }}\n" + strings.Replace(testErrorBody, "    name:", "\tname:", 1) + "{{- end }}\n",
+			expectLine:    6,
+			expectMessage: "invalid YAML",
+		},
+		{
+			name:             "content outside",
+			filename:         "secret-example.testing.yaml",
+			environment:      "testing",
+			data:             "{{- if eq .Values.environment \"testing\" }}\n" + testErrorBody + "{{- end }}\nfoo: bar\n",
+			expectLine:       10,
+			expectMessage:    "outside of a Helm condition",
+			expectSuggestion: "move it inside",
+		},
+		{
+			name:             "unsupported directive",
+			filename:         "secret-example.testing.yaml",
+			environment:      "testing",
+			data:             "{{- if eq .Values.environment \"testing\" }}\n" + testErrorBody + "{{ include \"labels\" . }}\n{{- end }}\n",
+			expectLine:       9,
+			expectMessage:    "unsupported Helm directive",
+			expectSuggestion: "only `if`, `else if` and `end`",
+		},
+		{
+			name:          "duplicate block",
+			filename:      "secret-example.testing.yaml",
+			environment:   "testing",
+			data:          "{{- if eq .Values.environment \"testing\" }}\n" + testErrorBody + "{{- else if eq .Values.environment \"testing\" }}\n" + testErrorBody + "{{- end }}\n",
+			expectLine:    9,
+			expectMessage: "more than one block for environment 'testing'",
+		},
+	}
+	for _, test := range tests {
+		_, err := parseSealedSecretTemplate(test.filename, test.environment, test.data, ProjectConfigDefault())
+		templateErr, ok := err.(*TemplateError)
+		if !ok {
+			t.Errorf("%s: expected a TemplateError, got: %v", test.name, err)
+			continue
+		}
+		if templateErr.Line != test.expectLine {
+			t.Errorf("%s: expected line %d, got %d: %s", test.name, test.expectLine, templateErr.Line, err)
+		}
+		if !strings.Contains(templateErr.Message, test.expectMessage) {
+			t.Errorf("%s: expected message containing '%s', got: %s", test.name, test.expectMessage, templateErr.Message)
+		}
+		if !strings.Contains(templateErr.Suggestion, test.expectSuggestion) {
+			t.Errorf("%s: expected suggestion containing '%s', got: %s", test.name, test.expectSuggestion, templateErr.Suggestion)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Encodings of a value given by --values-from.
+const (
+	ValueEncoding_String = "string"
+	ValueEncoding_Base64 = "base64"
+)
+
+// valueOptions are the sources secret values can be read from instead of
+// being typed at the prompt.
+type valueOptions struct {
+	fromEnvFile string
+	// valuesFrom is a JSON or YAML file of values, or - for stdin.
+	valuesFrom string
+	// fromEnv maps keys to the environment variable holding their value, and
+	// fromExec to the command whose stdout is their value.
+	fromEnv  keyValueFlag
+	fromExec keyValueFlag
+	// trim trims whitespace from values read from environment variables and
+	// commands.
+	trim bool
+	// confirm shows the values read for review, as when typed.
+	confirm bool
+}
+
+func (o *valueOptions) flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "from-env-file", Destination: &o.fromEnvFile, Usage: "read values from a dotenv `file` instead of prompting"},
+		&cli.StringFlag{Name: "values-from", Destination: &o.valuesFrom, Usage: "read values from a JSON or YAML object in `file`, or - for stdin, instead of prompting"},
+		&cli.GenericFlag{Name: "from-env", Value: &o.fromEnv, Usage: "read the value of a key from an environment variable, `KEY=VARNAME` (repeatable)"},
+		&cli.GenericFlag{Name: "from-exec", Value: &o.fromExec, Usage: "read the value of a key from the output of a command, `KEY=command` (repeatable)"},
+		&cli.BoolFlag{Name: "trim", Destination: &o.trim, Usage: "trim whitespace from values read by --from-env and --from-exec"},
+		&cli.BoolFlag{Name: "confirm", Destination: &o.confirm, Usage: "review values read by the --from and --values-from flags before sealing them"},
+	}
+}
+
+// given returns whether any source of values was given.
+func (o valueOptions) given() bool {
+	return o.fromEnvFile != "" || o.valuesFrom != "" || len(o.fromEnv) > 0 || len(o.fromExec) > 0
+}
+
+// fromStdin returns whether values are read from stdin, which then can't be
+// used for prompts.
+func (o valueOptions) fromStdin() bool {
+	return o.valuesFrom == "-"
+}
+
+// valueEntry is a value read from a source, which describes where it was
+// read from for errors.
+type valueEntry struct {
+	key    string
+	value  string
+	source string
+}
+
+// load sets values from the sources given. If secrets already has keys, as
+// when rotating, only those keys may be set and the others are unchanged,
+// otherwise every key read is added.
+func (o valueOptions) load(secrets *PromptSecrets, stdin io.Reader) error {
+	if o.confirm && o.fromStdin() {
+		return fmt.Errorf("--confirm can't be used with --values-from -, as stdin is used for values")
+	}
+	entries, err := o.entries(stdin)
+	if err != nil {
+		return err
+	}
+	add := len(secrets.secrets) == 0
+	for i := range secrets.secrets {
+		secrets.secrets[i].kind = PromptSecretInput_Kind_None
+	}
+	sources := map[string]string{}
+	for _, entry := range entries {
+		if entry.value == "" {
+			return fmt.Errorf("%s: %s has an empty value", entry.source, entry.key)
+		}
+		if source, exists := sources[entry.key]; exists {
+			return fmt.Errorf("%s: %s is already set by %s", entry.source, entry.key, source)
+		}
+		sources[entry.key] = entry.source
+		if err := secrets.Set(entry.key, entry.value, entry.source, add); err != nil {
+			return fmt.Errorf("%s: %s", entry.source, err)
+		}
+	}
+	return nil
+}
+
+func (o valueOptions) entries(stdin io.Reader) (entries []valueEntry, err error) {
+	if o.fromEnvFile != "" {
+		dotenv, err := loadDotenv(o.fromEnvFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range dotenv {
+			entries = append(entries, valueEntry{
+				key:    entry.Key,
+				value:  entry.Value,
+				source: fmt.Sprintf("%s:%d", o.fromEnvFile, entry.Line),
+			})
+		}
+	}
+	if o.valuesFrom != "" {
+		var content []byte
+		name := o.valuesFrom
+		if o.fromStdin() {
+			name = "stdin"
+			content, err = io.ReadAll(stdin)
+		} else {
+			content, err = os.ReadFile(o.valuesFrom)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read values from %s: %s", name, err)
+		}
+		structured, err := parseStructuredValues(content, name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, structured...)
+	}
+	for _, key := range sortedKeys(o.fromEnv) {
+		source := "--from-env " + key
+		if err := validateSecretKey(key); err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+		value, exists := os.LookupEnv(o.fromEnv[key])
+		if !exists {
+			return nil, fmt.Errorf("%s: environment variable %s is not set", source, o.fromEnv[key])
+		}
+		entries = append(entries, valueEntry{key: key, value: o.trimmed(value), source: source})
+	}
+	for _, key := range sortedKeys(o.fromExec) {
+		source := "--from-exec " + key
+		if err := validateSecretKey(key); err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+		value, err := execValue(o.fromExec[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+		entries = append(entries, valueEntry{key: key, value: o.trimmed(value), source: source})
+	}
+	return
+}
+
+func (o valueOptions) trimmed(value string) string {
+	if o.trim {
+		return strings.TrimSpace(value)
+	}
+	return value
+}
+
+// execValue runs a command with the shell and returns its stdout exactly,
+// failing if it exits non-zero. Its stderr is shown, as it may prompt for a
+// passphrase.
+func execValue(command string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("command failed: %s", err)
+	}
+	return stdout.String(), nil
+}
+
+// parseStructuredValues parses a JSON or YAML object of key to value. A value
+// is either a string, or an object with the string in `value` and its
+// `encoding`, string (default) or base64 for binary values.
+func parseStructuredValues(content []byte, name string) (entries []valueEntry, err error) {
+	var document yaml.Node
+	if err = yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("%s: invalid JSON or YAML: %s", name, err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected an object of key to value", name)
+	}
+	mapping := document.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, valueNode := mapping.Content[i], mapping.Content[i+1]
+		entry := valueEntry{key: keyNode.Value, source: fmt.Sprintf("%s:%d", name, keyNode.Line)}
+		if err = validateSecretKey(entry.key); err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.source, err)
+		}
+		entry.value, err = structuredValue(entry.key, valueNode)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.source, err)
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
+
+func structuredValue(key string, node *yaml.Node) (string, error) {
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Tag != "!!null":
+		return node.Value, nil
+	case node.Kind != yaml.MappingNode:
+		return "", fmt.Errorf("%s must be a string or an object with a value and encoding", key)
+	}
+	var value struct {
+		Value    *string `yaml:"value"`
+		Encoding string  `yaml:"encoding"`
+	}
+	if err := node.Decode(&value); err != nil {
+		return "", fmt.Errorf("%s: %s", key, err)
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if field := node.Content[i].Value; field != "value" && field != "encoding" {
+			return "", fmt.Errorf("%s has unknown field '%s', expected value and encoding", key, field)
+		}
+	}
+	if value.Value == nil {
+		return "", fmt.Errorf("%s has no value", key)
+	}
+	switch value.Encoding {
+	case "", ValueEncoding_String:
+		return *value.Value, nil
+	case ValueEncoding_Base64:
+		decoded, err := base64.StdEncoding.DecodeString(*value.Value)
+		if err != nil {
+			return "", fmt.Errorf("%s is not valid base64: %s", key, err)
+		}
+		return string(decoded), nil
+	}
+	return "", fmt.Errorf("%s has unknown encoding '%s', expected %s or %s", key, value.Encoding, ValueEncoding_String, ValueEncoding_Base64)
+}
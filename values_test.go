@@ -0,0 +1,169 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValueOptionsLoad(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env")
+	writeTestFile(t, envFile, "PASSWORD=secret\nTOKEN=\"abc\\n123\"\n")
+
+	secrets := PromptSecrets{}
+	err := valueOptions{fromEnvFile: envFile}.load(&secrets, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := map[string]string{"PASSWORD": "secret", "TOKEN": "abc\n123"}
+	if !reflect.DeepEqual(secrets.ToValues(), expect) {
+		t.Errorf("Expected %v, got %v", expect, secrets.ToValues())
+	}
+
+	secrets = PromptSecrets{}
+	secrets.InitKey("PASSWORD")
+	secrets.InitKey("TOKEN")
+	secrets.InitKey("USERNAME")
+	err = valueOptions{fromEnvFile: envFile}.load(&secrets, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	set, unchanged := secrets.Keys()
+	if !reflect.DeepEqual(set, []string{"PASSWORD", "TOKEN"}) || !reflect.DeepEqual(unchanged, []string{"USERNAME"}) {
+		t.Errorf("Expected PASSWORD and TOKEN set and USERNAME unchanged, got %v and %v", set, unchanged)
+	}
+	if !reflect.DeepEqual(secrets.ToValues(), expect) {
+		t.Errorf("Expected %v, got %v", expect, secrets.ToValues())
+	}
+
+	secrets = PromptSecrets{}
+	secrets.InitKey("PASSWORD")
+	err = valueOptions{fromEnvFile: envFile}.load(&secrets, nil)
+	if err == nil || !strings.Contains(err.Error(), ".env:2: key 'TOKEN' is not in spec.encryptedData") {
+		t.Errorf("Expected error for key not being rotated, got: %v", err)
+	}
+
+	writeTestFile(t, envFile, "PASSWORD=\n")
+	err = valueOptions{fromEnvFile: envFile}.load(&PromptSecrets{}, nil)
+	if err == nil || !strings.Contains(err.Error(), ".env:1: PASSWORD has an empty value") {
+		t.Errorf("Expected error for empty value, got: %v", err)
+	}
+}
+
+func TestParseStructuredValues(t *testing.T) {
+	tests := []struct {
+		content string
+		expect  []valueEntry
+		err     string
+	}{
+		{
+			content: `{"PASSWORD": "secret", "PORT": 5432, "KEY": {"value": "AAEC/w==", "encoding": "base64"}}`,
+			expect: []valueEntry{
+				{"PASSWORD", "secret", "stdin:1"},
+				{"PORT", "5432", "stdin:1"},
+				{"KEY", "\x00\x01\x02\xff", "stdin:1"},
+			},
+		},
+		{
+			content: "PASSWORD: secret\nCERT: |\n  line 1\n  line 2\nTOKEN:\n  value: abc\n  encoding: string\n",
+			expect: []valueEntry{
+				{"PASSWORD", "secret", "stdin:1"},
+				{"CERT", "line 1\nline 2\n", "stdin:2"},
+				{"TOKEN", "abc", "stdin:5"},
+			},
+		},
+		{content: "", err: "stdin: expected an object of key to value"},
+		{content: "- a\n- b\n", err: "stdin: expected an object of key to value"},
+		{content: "{", err: "stdin: invalid JSON or YAML"},
+		{content: "A: null\n", err: "stdin:1: A must be a string"},
+		{content: "A: [1]\n", err: "stdin:1: A must be a string"},
+		{content: "A: {encoding: base64}\n", err: "stdin:1: A has no value"},
+		{content: "A: {value: '!!', encoding: base64}\n", err: "stdin:1: A is not valid base64"},
+		{content: "A: {value: x, encoding: hex}\n", err: "stdin:1: A has unknown encoding 'hex'"},
+		{content: "A: {value: x, format: y}\n", err: "stdin:1: A has unknown field 'format'"},
+		{content: "BAD KEY: x\n", err: "stdin:1: "},
+	}
+	for _, test := range tests {
+		entries, err := parseStructuredValues([]byte(test.content), "stdin")
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("Expected error starting '%s' for %q, got: %v", test.err, test.content, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", test.content, err)
+		} else if !reflect.DeepEqual(entries, test.expect) {
+			t.Errorf("Expected %#v, got %#v", test.expect, entries)
+		}
+	}
+}
+
+func TestValueOptionsLoadStdin(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env")
+	writeTestFile(t, envFile, "PASSWORD=secret\n")
+
+	secrets := PromptSecrets{}
+	err := valueOptions{valuesFrom: "-"}.load(&secrets, strings.NewReader(`{"TOKEN": "abc"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expect := map[string]string{"TOKEN": "abc"}; !reflect.DeepEqual(secrets.ToValues(), expect) {
+		t.Errorf("Expected %v, got %v", expect, secrets.ToValues())
+	}
+
+	err = valueOptions{fromEnvFile: envFile, valuesFrom: "-"}.load(&PromptSecrets{}, strings.NewReader("PASSWORD: other\n"))
+	if err == nil || !strings.Contains(err.Error(), "stdin:1: PASSWORD is already set by "+envFile+":1") {
+		t.Errorf("Expected error for key set twice, got: %v", err)
+	}
+
+	err = valueOptions{valuesFrom: "-", confirm: true}.load(&PromptSecrets{}, strings.NewReader(`{"TOKEN": "abc"}`))
+	if err == nil || !strings.Contains(err.Error(), "--confirm can't be used with --values-from -") {
+		t.Errorf("Expected error for --confirm with stdin, got: %v", err)
+	}
+}
+
+func TestValueOptionsLoadEnvAndExec(t *testing.T) {
+	t.Setenv("TEST_KUBESEALPLUS_PASSWORD", " secret\n")
+	options := valueOptions{
+		fromEnv:  keyValueFlag{"PASSWORD": "TEST_KUBESEALPLUS_PASSWORD"},
+		fromExec: keyValueFlag{"TOKEN": "printf 'abc\\n'"},
+	}
+	secrets := PromptSecrets{}
+	if err := options.load(&secrets, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := map[string]string{"PASSWORD": " secret\n", "TOKEN": "abc\n"}
+	if !reflect.DeepEqual(secrets.ToValues(), expect) {
+		t.Errorf("Expected %q, got %q", expect, secrets.ToValues())
+	}
+
+	options.trim = true
+	secrets = PromptSecrets{}
+	if err := options.load(&secrets, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect = map[string]string{"PASSWORD": "secret", "TOKEN": "abc"}
+	if !reflect.DeepEqual(secrets.ToValues(), expect) {
+		t.Errorf("Expected %q, got %q", expect, secrets.ToValues())
+	}
+
+	tests := []struct {
+		options valueOptions
+		err     string
+	}{
+		{valueOptions{fromEnv: keyValueFlag{"A": "TEST_KUBESEALPLUS_UNSET"}}, "--from-env A: environment variable TEST_KUBESEALPLUS_UNSET is not set"},
+		{valueOptions{fromExec: keyValueFlag{"A": "echo partial; exit 3"}}, "--from-exec A: command failed: exit status 3"},
+		{valueOptions{fromExec: keyValueFlag{"A": "true"}}, "--from-exec A: A has an empty value"},
+		{valueOptions{fromExec: keyValueFlag{"bad key": "echo x"}}, "--from-exec bad key: "},
+	}
+	for _, test := range tests {
+		err := test.options.load(&PromptSecrets{}, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Expected error starting '%s', got: %v", test.err, err)
+		}
+	}
+}
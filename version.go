@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Set by goreleaser via ldflags, see .goreleaser.yaml.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+	builtBy = "unknown"
+)
+
+// VersionInfo describes the build of kubesealplus and the kubeseal binary it
+// seals secrets with.
+type VersionInfo struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	Date            string `json:"date"`
+	BuiltBy         string `json:"builtBy"`
+	GoVersion       string `json:"goVersion"`
+	KubesealVersion string `json:"kubesealVersion"`
+}
+
+// versionInfo returns the build metadata, falling back to what the Go
+// toolchain recorded for builds not made by goreleaser, e.g. go install.
+func versionInfo() VersionInfo {
+	info := VersionInfo{
+		Version:         version,
+		Commit:          commit,
+		Date:            date,
+		BuiltBy:         builtBy,
+		GoVersion:       runtime.Version(),
+		KubesealVersion: kubesealVersion(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "none":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "unknown":
+				info.Date = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// kubesealVersion runs kubeseal --version, returning "not found" if kubeseal
+// is not installed.
+func kubesealVersion() string {
+	ctx, timeout := context.WithTimeout(context.Background(), 2*time.Second)
+	defer timeout()
+	out, err := exec.CommandContext(ctx, "kubeseal", "--version").Output()
+	if err != nil {
+		if _, lookErr := exec.LookPath("kubeseal"); lookErr != nil {
+			return "not found"
+		}
+		return fmt.Sprintf("unknown (%s)", err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(out)), "kubeseal version:"))
+}
+
+func (v VersionInfo) Write(w io.Writer, output string) error {
+	switch output {
+	case OutputFormat_Text:
+		fmt.Fprintf(w, "kubesealplus %s\n", v.Version)
+		fmt.Fprintf(w, "  commit:   %s\n", v.Commit)
+		fmt.Fprintf(w, "  built:    %s by %s\n", v.Date, v.BuiltBy)
+		fmt.Fprintf(w, "  go:       %s\n", v.GoVersion)
+		fmt.Fprintf(w, "  kubeseal: %s\n", v.KubesealVersion)
+		return nil
+	case OutputFormat_JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	return checkOutputFormat(output, OutputFormat_Text, OutputFormat_JSON)
+}
+
+func versionCommand() *cli.Command {
+	var output string
+	formats := []string{OutputFormat_Text, OutputFormat_JSON}
+	return &cli.Command{
+		Name:  "version",
+		Usage: "print the version of kubesealplus and kubeseal",
+		Flags: []cli.Flag{outputFlag(&output, formats...)},
+		Action: func(c *cli.Context) error {
+			return versionInfo().Write(c.App.Writer, output)
+		},
+		BashComplete: completeCommand(completeOutputFormats(nil, formats...), func(c *cli.Context, args []string) {}),
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestVersionInfoWrite(t *testing.T) {
+	info := VersionInfo{
+		Version:         "1.2.3",
+		Commit:          "abc123",
+		Date:            "2023-03-01T00:00:00Z",
+		BuiltBy:         "goreleaser",
+		GoVersion:       "go1.20",
+		KubesealVersion: "0.19.5",
+	}
+
+	text := &bytes.Buffer{}
+	if err := info.Write(text, OutputFormat_Text); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, expect := range []string{"kubesealplus 1.2.3\n", "abc123", "goreleaser", "go1.20", "kubeseal: 0.19.5"} {
+		if !strings.Contains(text.String(), expect) {
+			t.Errorf("Expected text output to contain '%s', got:\n%s", expect, text.String())
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if err := info.Write(out, OutputFormat_JSON); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var got VersionInfo
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil || got != info {
+		t.Errorf("Expected JSON output to round trip, got %+v (%v)", got, err)
+	}
+
+	if err := info.Write(&bytes.Buffer{}, "yaml"); err == nil {
+		t.Errorf("Expected error for unknown output format")
+	}
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlDocument is a single YAML document kept alongside its source text, so
+// that targeted edits can be spliced into the original text. Everything not
+// edited (comments, key order, quoting, fields we do not model) is left
+// byte-for-byte as it was. When an edit cannot be spliced safely the document
+// is re-encoded from its node tree instead, which keeps comments and key order
+// but may change formatting.
+type yamlDocument struct {
+	source string
+	root   *yaml.Node
+}
+
+func parseYAMLDocument(source string) (doc *yamlDocument, err error) {
+	doc = &yamlDocument{source: source}
+	err = doc.parse()
+	return
+}
+
+func (d *yamlDocument) parse() error {
+	var node yaml.Node
+	err := yaml.Unmarshal([]byte(d.source), &node)
+	if err != nil {
+		return err
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) != 1 || node.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("expected YAML document to contain a mapping")
+	}
+	d.root = node.Content[0]
+	return nil
+}
+
+func (d *yamlDocument) String() string {
+	return d.source
+}
+
+func (d *yamlDocument) Decode(v interface{}) error {
+	return d.root.Decode(v)
+}
+
+// Mapping returns the mapping node found by following the given keys from the
+// document root, or nil if there is no such mapping.
+func (d *yamlDocument) Mapping(path ...string) *yaml.Node {
+	node := d.root
+	for _, key := range path {
+		_, node = yamlMappingEntry(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	return node
+}
+
+// Get returns the scalar value of key within the mapping at path.
+func (d *yamlDocument) Get(path []string, key string) (value string, exists bool) {
+	mapping := d.Mapping(path...)
+	if mapping == nil {
+		return
+	}
+	_, node := yamlMappingEntry(mapping, key)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return
+	}
+	return node.Value, true
+}
+
+// Line returns the line of key within the mapping at path, counting from 1
+// at the start of the document, or 0 if there is no such key.
+func (d *yamlDocument) Line(path []string, key string) int {
+	mapping := d.Mapping(path...)
+	if mapping == nil {
+		return 0
+	}
+	keyNode, _ := yamlMappingEntry(mapping, key)
+	if keyNode == nil {
+		return 0
+	}
+	return keyNode.Line
+}
+
+// Keys returns the keys of the mapping at path in document order.
+func (d *yamlDocument) Keys(path ...string) (keys []string) {
+	mapping := d.Mapping(path...)
+	if mapping == nil {
+		return
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keys = append(keys, mapping.Content[i].Value)
+	}
+	return
+}
+
+// Set sets key to a string value within the mapping at path, creating the
+// key if it does not already exist.
+func (d *yamlDocument) Set(path []string, key string, value string) error {
+	mapping := d.Mapping(path...)
+	if mapping == nil {
+		return fmt.Errorf("cannot set '%s': no mapping at '%s'", key, strings.Join(path, "."))
+	}
+	keyNode, valueNode := yamlMappingEntry(mapping, key)
+	if valueNode != nil && valueNode.Kind == yaml.ScalarNode && valueNode.Value == value && valueNode.Tag == "!!str" {
+		return nil
+	}
+	if valueNode != nil {
+		if valueNode.Kind == yaml.ScalarNode && d.spliceScalar(valueNode, value) {
+			return d.parse()
+		}
+		if d.spliceReplace(keyNode, valueNode, key, value) {
+			return d.parse()
+		}
+		*valueNode = *yamlStringNode(value)
+		return d.reencode()
+	}
+	if d.spliceInsert(mapping, key, value) {
+		return d.parse()
+	}
+	if len(mapping.Content) == 0 {
+		mapping.Style &^= yaml.FlowStyle
+	}
+	mapping.Content = append(mapping.Content, yamlStringNode(key), yamlStringNode(value))
+	return d.reencode()
+}
+
+// EnsureMapping creates an empty mapping at path if there is none, replacing
+// a missing or null value.
+func (d *yamlDocument) EnsureMapping(path ...string) error {
+	if d.Mapping(path...) != nil {
+		return nil
+	}
+	for i := range path {
+		if d.Mapping(path[:i+1]...) != nil {
+			continue
+		}
+		parent := d.Mapping(path[:i]...)
+		if parent == nil {
+			return fmt.Errorf("no mapping at '%s'", strings.Join(path[:i], "."))
+		}
+		empty := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}
+		_, valueNode := yamlMappingEntry(parent, path[i])
+		if valueNode == nil {
+			parent.Content = append(parent.Content, yamlStringNode(path[i]), empty)
+		} else if valueNode.Kind == yaml.ScalarNode && valueNode.Tag == "!!null" {
+			*valueNode = *empty
+		} else {
+			return fmt.Errorf("'%s' is not a mapping", strings.Join(path[:i+1], "."))
+		}
+	}
+	return d.reencode()
+}
+
+// Delete removes key from the mapping at path, if it exists.
+func (d *yamlDocument) Delete(path []string, key string) error {
+	mapping := d.Mapping(path...)
+	if mapping == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if len(mapping.Content) > 2 && d.spliceDelete(mapping.Content[i], mapping.Content[i+1]) {
+			return d.parse()
+		}
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		if len(mapping.Content) == 0 {
+			mapping.Style = yaml.FlowStyle
+		}
+		return d.reencode()
+	}
+	return nil
+}
+
+// Rename renames key within the mapping at path, keeping its value.
+func (d *yamlDocument) Rename(path []string, key string, newKey string) error {
+	mapping := d.Mapping(path...)
+	if mapping == nil {
+		return fmt.Errorf("cannot rename '%s': no mapping at '%s'", key, strings.Join(path, "."))
+	}
+	if existing, _ := yamlMappingEntry(mapping, newKey); existing != nil {
+		return fmt.Errorf("cannot rename '%s': key '%s' already exists", key, newKey)
+	}
+	keyNode, _ := yamlMappingEntry(mapping, key)
+	if keyNode == nil {
+		return fmt.Errorf("cannot rename '%s': key does not exist", key)
+	}
+	if d.spliceScalar(keyNode, newKey) {
+		return d.parse()
+	}
+	keyNode.Value = newKey
+	return d.reencode()
+}
+
+// SortKeys sorts the entries of the mapping at path by key. Entries are moved
+// as whole lines where possible, keeping their formatting.
+func (d *yamlDocument) SortKeys(path ...string) error {
+	mapping := d.Mapping(path...)
+	if mapping == nil || len(mapping.Content) < 4 {
+		return nil
+	}
+	type entry struct {
+		key       string
+		first     int
+		last      int
+		keyNode   *yaml.Node
+		valueNode *yaml.Node
+	}
+	entries := []entry{}
+	sorted := true
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		e := entry{
+			key:       mapping.Content[i].Value,
+			first:     mapping.Content[i].Line,
+			last:      yamlNodeEndLine(mapping.Content[i+1]),
+			keyNode:   mapping.Content[i],
+			valueNode: mapping.Content[i+1],
+		}
+		if len(entries) > 0 && entries[len(entries)-1].key > e.key {
+			sorted = false
+		}
+		entries = append(entries, e)
+	}
+	if sorted {
+		return nil
+	}
+	contiguous := mapping.Style&yaml.FlowStyle == 0
+	for i := 1; i < len(entries); i++ {
+		if entries[i].first != entries[i-1].last+1 {
+			contiguous = false
+		}
+	}
+	offsets := d.lineOffsets()
+	last := entries[len(entries)-1].last
+	if contiguous && last < len(offsets) {
+		chunks := map[string]string{}
+		keys := []string{}
+		for _, e := range entries {
+			chunks[e.key] = d.source[offsets[e.first-1]:offsets[e.last]]
+			keys = append(keys, e.key)
+		}
+		sort.Strings(keys)
+		out := strings.Builder{}
+		for _, key := range keys {
+			out.WriteString(chunks[key])
+		}
+		d.source = d.source[:offsets[entries[0].first-1]] + out.String() + d.source[offsets[last]:]
+		return d.parse()
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	mapping.Content = mapping.Content[:0]
+	for _, e := range entries {
+		mapping.Content = append(mapping.Content, e.keyNode, e.valueNode)
+	}
+	return d.reencode()
+}
+
+// DeleteNulls removes every mapping entry with a null value.
+func (d *yamlDocument) DeleteNulls() error {
+	for {
+		path, key, found := yamlFindNull(d.root, []string{})
+		if !found {
+			return nil
+		}
+		err := d.Delete(path, key)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func yamlFindNull(mapping *yaml.Node, path []string) ([]string, string, bool) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i].Value, mapping.Content[i+1]
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+			return path, key, true
+		}
+		if value.Kind == yaml.MappingNode {
+			nested := append(append([]string{}, path...), key)
+			if p, k, found := yamlFindNull(value, nested); found {
+				return p, k, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// Reformat re-encodes the whole document with the given indentation.
+func (d *yamlDocument) Reformat(indent int) error {
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	err := encoder.Encode(d.root)
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		return err
+	}
+	d.source = buf.String()
+	return d.parse()
+}
+
+func yamlStringNode(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if strings.Contains(value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	return node
+}
+
+func yamlMappingEntry(mapping *yaml.Node, key string) (keyNode *yaml.Node, valueNode *yaml.Node) {
+	if mapping.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return
+}
+
+// reencode rewrites the whole document from its node tree, preserving the
+// indentation already used by the document.
+func (d *yamlDocument) reencode() error {
+	return d.Reformat(yamlDetectIndent(d.source))
+}
+
+// lineOffsets returns the byte offset of the start of each line in source.
+func (d *yamlDocument) lineOffsets() []int {
+	offsets := []int{0}
+	for i, c := range d.source {
+		if c == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// scalarSpan returns the byte range of a single line scalar node's raw text.
+func (d *yamlDocument) scalarSpan(node *yaml.Node) (start int, end int, ok bool) {
+	offsets := d.lineOffsets()
+	if node.Line < 1 || node.Line > len(offsets) {
+		return
+	}
+	start = offsets[node.Line-1] + node.Column - 1
+	lineEnd := strings.IndexByte(d.source[start:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(d.source)
+	} else {
+		lineEnd += start
+	}
+	raw := d.source[start:lineEnd]
+	switch node.Style {
+	case 0:
+		if !strings.HasPrefix(raw, node.Value) {
+			return
+		}
+		end = start + len(node.Value)
+	case yaml.DoubleQuotedStyle:
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '\\' {
+				i++
+			} else if raw[i] == '"' {
+				end = start + i + 1
+				break
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '\'' {
+				if i+1 < len(raw) && raw[i+1] == '\'' {
+					i++
+					continue
+				}
+				end = start + i + 1
+				break
+			}
+		}
+	}
+	ok = end > start
+	return
+}
+
+// yamlScalar renders value as a single line scalar, using the given style
+// where the value allows it.
+func yamlScalar(value string, style yaml.Style) (string, bool) {
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: style})
+	if err != nil {
+		return "", false
+	}
+	rendered := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(rendered, "\n") {
+		return "", false
+	}
+	return rendered, true
+}
+
+func (d *yamlDocument) spliceScalar(node *yaml.Node, value string) bool {
+	start, end, ok := d.scalarSpan(node)
+	if !ok {
+		return false
+	}
+	rendered, ok := yamlScalar(value, node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle))
+	if !ok {
+		return false
+	}
+	d.source = d.source[:start] + rendered + d.source[end:]
+	return true
+}
+
+// renderEntry renders a single mapping entry indented to the given column.
+func (d *yamlDocument) renderEntry(key string, value string, column int) (string, bool) {
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(yamlDetectIndent(d.source))
+	err := encoder.Encode(&yaml.Node{
+		Kind:    yaml.MappingNode,
+		Content: []*yaml.Node{yamlStringNode(key), yamlStringNode(value)},
+	})
+	if err != nil || encoder.Close() != nil {
+		return "", false
+	}
+	indent := strings.Repeat(" ", column-1)
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, ""), true
+}
+
+// spliceInsert adds a new key after the last entry of a block style mapping,
+// using the same indentation as the existing keys.
+func (d *yamlDocument) spliceInsert(mapping *yaml.Node, key string, value string) bool {
+	if mapping.Style&yaml.FlowStyle != 0 || len(mapping.Content) == 0 {
+		return false
+	}
+	entry, ok := d.renderEntry(key, value, mapping.Content[0].Column)
+	if !ok {
+		return false
+	}
+	lastLine := yamlNodeEndLine(mapping.Content[len(mapping.Content)-1])
+	offsets := d.lineOffsets()
+	if lastLine < 1 || lastLine > len(offsets) {
+		return false
+	}
+	if lastLine == len(offsets) {
+		if !strings.HasSuffix(d.source, "\n") {
+			d.source += "\n"
+		}
+		d.source += entry
+		return true
+	}
+	insertAt := offsets[lastLine]
+	d.source = d.source[:insertAt] + entry + d.source[insertAt:]
+	return true
+}
+
+// spliceReplace replaces the lines holding a mapping entry with a newly
+// rendered entry, for values which cannot be replaced within a single line.
+func (d *yamlDocument) spliceReplace(keyNode *yaml.Node, valueNode *yaml.Node, key string, value string) bool {
+	entry, ok := d.renderEntry(key, value, keyNode.Column)
+	if !ok || !d.spliceDelete(keyNode, valueNode) {
+		return false
+	}
+	offsets := d.lineOffsets()
+	insertAt := len(d.source)
+	if keyNode.Line <= len(offsets) {
+		insertAt = offsets[keyNode.Line-1]
+	}
+	d.source = d.source[:insertAt] + entry + d.source[insertAt:]
+	return true
+}
+
+// spliceDelete removes the lines holding a mapping entry.
+func (d *yamlDocument) spliceDelete(keyNode *yaml.Node, valueNode *yaml.Node) bool {
+	offsets := d.lineOffsets()
+	firstLine := keyNode.Line
+	lastLine := yamlNodeEndLine(valueNode)
+	if firstLine < 1 || lastLine < firstLine || lastLine > len(offsets) {
+		return false
+	}
+	prefix := d.source[offsets[firstLine-1] : offsets[firstLine-1]+keyNode.Column-1]
+	if strings.TrimSpace(prefix) != "" {
+		return false
+	}
+	end := len(d.source)
+	if lastLine < len(offsets) {
+		end = offsets[lastLine]
+	}
+	d.source = d.source[:offsets[firstLine-1]] + d.source[end:]
+	return true
+}
+
+// yamlNodeEndLine returns the last line a block style node occupies.
+func yamlNodeEndLine(node *yaml.Node) int {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		if len(node.Content) == 0 || node.Style&yaml.FlowStyle != 0 {
+			return node.Line
+		}
+		return yamlNodeEndLine(node.Content[len(node.Content)-1])
+	case yaml.ScalarNode:
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return node.Line + strings.Count(strings.TrimSuffix(node.Value, "\n"), "\n") + 1
+		}
+	}
+	return node.Line
+}
+
+// yamlDetectIndent returns the indentation width used by the first nested
+// line of a YAML document, defaulting to the yaml.v3 default of 4.
+func yamlDetectIndent(source string) int {
+	for _, line := range strings.Split(source, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || len(trimmed) == len(line) {
+			continue
+		}
+		return len(line) - len(trimmed)
+	}
+	return 4
+}
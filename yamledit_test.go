@@ -0,0 +1,187 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestYAMLDocumentSet(t *testing.T) {
+	source := "" +
+		"# leading comment\n" +
+		"metadata:\n" +
+		"  name: example-secret # trailing comment\n" +
+		"  labels: {app: example}\n" +
+		"spec:\n" +
+		"  encryptedData:\n" +
+		"    A: \"old-a\"\n" +
+		"    B: old-b\n" +
+		"  template:\n" +
+		"    type: kubernetes.io/tls\n"
+	tests := []struct {
+		key    string
+		value  string
+		expect string
+	}{
+		{
+			key:   "A",
+			value: "new-a",
+			expect: "" +
+				"# leading comment\n" +
+				"metadata:\n" +
+				"  name: example-secret # trailing comment\n" +
+				"  labels: {app: example}\n" +
+				"spec:\n" +
+				"  encryptedData:\n" +
+				"    A: \"new-a\"\n" +
+				"    B: old-b\n" +
+				"  template:\n" +
+				"    type: kubernetes.io/tls\n",
+		},
+		{
+			key:   "B",
+			value: "new-b",
+			expect: "" +
+				"# leading comment\n" +
+				"metadata:\n" +
+				"  name: example-secret # trailing comment\n" +
+				"  labels: {app: example}\n" +
+				"spec:\n" +
+				"  encryptedData:\n" +
+				"    A: \"old-a\"\n" +
+				"    B: new-b\n" +
+				"  template:\n" +
+				"    type: kubernetes.io/tls\n",
+		},
+		{
+			key:   "C",
+			value: "new-c",
+			expect: "" +
+				"# leading comment\n" +
+				"metadata:\n" +
+				"  name: example-secret # trailing comment\n" +
+				"  labels: {app: example}\n" +
+				"spec:\n" +
+				"  encryptedData:\n" +
+				"    A: \"old-a\"\n" +
+				"    B: old-b\n" +
+				"    C: new-c\n" +
+				"  template:\n" +
+				"    type: kubernetes.io/tls\n",
+		},
+	}
+	for _, test := range tests {
+		doc, err := parseYAMLDocument(source)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		err = doc.Set([]string{"spec", "encryptedData"}, test.key, test.value)
+		if err != nil {
+			t.Errorf("Unexpected error setting %s: %s", test.key, err)
+		}
+		if doc.String() != test.expect {
+			t.Errorf("Setting %s.\nExpected:\n%s\nGot:\n%s", test.key, test.expect, doc.String())
+		}
+	}
+}
+
+func TestYAMLDocumentDeleteAndRename(t *testing.T) {
+	source := "" +
+		"spec:\n" +
+		"    encryptedData:\n" +
+		"        A: a\n" +
+		"        B: b\n" +
+		"    template: {}\n"
+	doc, err := parseYAMLDocument(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err = doc.Rename([]string{"spec", "encryptedData"}, "A", "B"); err == nil {
+		t.Errorf("Expected error renaming to an existing key")
+	}
+	if err = doc.Rename([]string{"spec", "encryptedData"}, "A", "C"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err = doc.Delete([]string{"spec", "encryptedData"}, "B"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expect := "" +
+		"spec:\n" +
+		"    encryptedData:\n" +
+		"        C: a\n" +
+		"    template: {}\n"
+	if doc.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, doc.String())
+	}
+	if err = doc.Delete([]string{"spec", "encryptedData"}, "C"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expect = "" +
+		"spec:\n" +
+		"    encryptedData: {}\n" +
+		"    template: {}\n"
+	if doc.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, doc.String())
+	}
+}
+
+func TestYAMLDocumentSortKeysAndDeleteNulls(t *testing.T) {
+	source := "" +
+		"metadata:\n" +
+		"  creationTimestamp: null\n" +
+		"  name: example-secret\n" +
+		"spec:\n" +
+		"  encryptedData:\n" +
+		"    C: \"c\" # comment kept\n" +
+		"    A: a\n" +
+		"    B: |\n" +
+		"      multi\n" +
+		"      line\n" +
+		"  template:\n" +
+		"    data: null\n"
+	doc, err := parseYAMLDocument(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err = doc.SortKeys("spec", "encryptedData"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err = doc.DeleteNulls(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expect := "" +
+		"metadata:\n" +
+		"  name: example-secret\n" +
+		"spec:\n" +
+		"  encryptedData:\n" +
+		"    A: a\n" +
+		"    B: |\n" +
+		"      multi\n" +
+		"      line\n" +
+		"    C: \"c\" # comment kept\n" +
+		"  template: {}\n"
+	if doc.String() != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, doc.String())
+	}
+}
+
+func TestYAMLDocumentLine(t *testing.T) {
+	doc, err := parseYAMLDocument("metadata:\n    name: example\nspec:\n    encryptedData:\n        A: x\n        B: y\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	tests := []struct {
+		path   []string
+		key    string
+		expect int
+	}{
+		{[]string{"metadata"}, "name", 2},
+		{[]string{"spec", "encryptedData"}, "B", 6},
+		{nil, "spec", 3},
+		{[]string{"spec", "encryptedData"}, "C", 0},
+		{[]string{"missing"}, "name", 0},
+	}
+	for _, test := range tests {
+		if got := doc.Line(test.path, test.key); got != test.expect {
+			t.Errorf("Expected line %d for %v %s, got %d", test.expect, test.path, test.key, got)
+		}
+	}
+}